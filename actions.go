@@ -0,0 +1,609 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/td0m/taskman/format"
+	"github.com/td0m/taskman/pkg/dateinput"
+	"github.com/td0m/taskman/task"
+)
+
+// action is something to do in the normal mode, bound to keys and listed in
+// the command palette
+type action struct {
+	name string
+	// keys trigger the action, the first of them is the one shown
+	keys []string
+	help string
+	// args describes what the action takes after its name in the palette
+	args string
+	// run does it, with whatever was typed after its name in the palette
+	run func(m *app, args string) error
+}
+
+// badArgs is the error of an action given arguments it cannot make sense of,
+// which is only worth a warning
+type badArgs string
+
+func (e badArgs) Error() string {
+	return string(e)
+}
+
+var actions = append([]action{
+	{"down", []string{"j", tea.KeyDown.String()}, "move the cursor down", "", func(m *app, _ string) error {
+		m.setCursor(m.cursor + 1)
+		return nil
+	}},
+	{"up", []string{"k", tea.KeyUp.String()}, "move the cursor up", "", func(m *app, _ string) error {
+		m.setCursor(m.cursor - 1)
+		return nil
+	}},
+	{"new", []string{"o"}, "add a task below", "[title]", func(m *app, args string) error {
+		return m.add(task.Below, args)
+	}},
+	{"new-above", []string{"O"}, "add a task above", "[title]", func(m *app, args string) error {
+		return m.add(task.Above, args)
+	}},
+	{"edit", []string{"i"}, "change the title", "[title]", func(m *app, args string) error {
+		id := getID(m.atCursor())
+		if len(id) == 0 {
+			return nil
+		}
+		if args == "" {
+			m.edit()
+			return nil
+		}
+		if err := m.all.SetTitle(id, args); err != nil {
+			return err
+		}
+		// sorted by title, the task may have moved
+		m.updateVisible()
+		if i := m.indexOf(id); i >= 0 {
+			m.setCursor(i)
+		}
+		return nil
+	}},
+	{"toggle", []string{"t"}, "mark as done or not done", "", (*app).toggle},
+	{"due", []string{"d"}, "set the due date", "[date|none]", func(m *app, args string) error {
+		return m.date(dateMode, args)
+	}},
+	{"start", []string{"s"}, "set the start date", "[date|none]", func(m *app, args string) error {
+		return m.date(startMode, args)
+	}},
+	{"remind", []string{"r"}, "set reminders before the due date", "[reminders]", func(m *app, args string) error {
+		id := getID(m.atCursor())
+		if len(id) == 0 {
+			return nil
+		}
+		if args != "" {
+			if err := m.setReminders(args); err != nil {
+				return badArgs(err.Error())
+			}
+			return nil
+		}
+		m.mode = remindMode
+		m.textinput.SetValue(strings.Join(m.all.Nodes[id].Reminders, ", "))
+		m.textinput.Width = len(m.textinput.Value()) + 1
+		m.textinput.SetCursor(m.textinput.Width)
+		return nil
+	}},
+	{"snooze", []string{"z"}, "snooze reminders for an hour", "", func(m *app, _ string) error {
+		if id := getID(m.atCursor()); len(id) > 0 {
+			until := time.Now().Add(time.Hour)
+			if err := snooze(m.storage, id, until); err != nil {
+				return err
+			}
+			m.notice = "snoozed until " + until.Format("15:04")
+		}
+		return nil
+	}},
+	{"fold", []string{tea.KeyEnter.String()}, "fold or unfold the subtasks", "", func(m *app, _ string) error {
+		id := getID(m.atCursor())
+		if len(id) == 0 {
+			return nil
+		}
+		err := m.setFolded(id, !m.folded(id))
+		m.updateVisible()
+		return err
+	}},
+	{"move-up", []string{"K"}, "move above the previous task", "", func(m *app, _ string) error {
+		return m.moveBy(-1)
+	}},
+	{"move-down", []string{"J"}, "move below the next task", "", func(m *app, _ string) error {
+		return m.moveBy(1)
+	}},
+	{"indent", []string{tea.KeyTab.String()}, "make a subtask of the task above", "", (*app).indentCursor},
+	{"outdent", []string{tea.KeyShiftTab.String()}, "move out of the parent", "", (*app).outdentCursor},
+	{"move-to", nil, "move under another task", "<task>", func(m *app, args string) error {
+		id := getID(m.atCursor())
+		if len(id) == 0 {
+			return nil
+		}
+		parent, err := m.find(args, id)
+		if err != nil {
+			return err
+		}
+		if err := m.all.Move(id, parent, "", task.Below); err != nil {
+			return err
+		}
+		m.updateVisible()
+		m.notice = "moved under " + m.all.Nodes[parent].Title
+		return nil
+	}},
+	{"jump", nil, "go to a task", "<task>", func(m *app, args string) error {
+		id, err := m.find(args, "")
+		if err != nil {
+			return err
+		}
+		return m.jump(id)
+	}},
+	{"delete", []string{"delete"}, "delete with all subtasks", "", func(m *app, _ string) error {
+		id := getID(m.atCursor())
+		// refuse to delete anything until the last failure has been looked at
+		if len(id) == 0 || m.err != nil {
+			return nil
+		}
+		err := m.all.Remove(id)
+		m.updateVisible()
+		m.setCursor(m.cursor)
+		return err
+	}},
+	{"yank", []string{"y"}, "copy as a markdown checklist", "", func(m *app, _ string) error {
+		return m.yank()
+	}},
+	{"paste", []string{"p"}, "add the tasks of a markdown checklist", "", func(m *app, _ string) error {
+		err := m.paste()
+		m.updateVisible()
+		return err
+	}},
+	{"export", nil, "copy or save the tasks in a format", "<format> [file]", (*app).export},
+	{"hoist", []string{">"}, "show only the subtasks", "", func(m *app, _ string) error {
+		if id := getID(m.atCursor()); len(id) > 0 {
+			m.hoist(id)
+		}
+		return nil
+	}},
+	{"unhoist", []string{"<"}, "show the parent of the hoisted task", "", func(m *app, _ string) error {
+		m.unhoist()
+		return nil
+	}},
+	{"view", []string{"v"}, "switch to the next or a given view", "[name]", func(m *app, args string) error {
+		if args == "" {
+			m.setView((m.view + 1) % view(len(viewNames)))
+			return nil
+		}
+		for i, name := range viewNames {
+			if strings.HasPrefix(name, args) {
+				m.setView(view(i))
+				return nil
+			}
+		}
+		return badArgs("views are " + strings.Join(viewNames, ", "))
+	}},
+	{"sort", []string{"S"}, "sort the tree by due, created, priority, title or done", "[order]", func(m *app, args string) error {
+		names := []string{}
+		for _, o := range orderings {
+			names = append(names, o.name)
+		}
+		a := m.arrangement
+		var err error
+		a.order, err = cycle(names, a.order, args)
+		m.rearrange(a)
+		return err
+	}},
+	{"group", []string{"G"}, "group the tree by due, tag or project", "[grouping]", func(m *app, args string) error {
+		names := []string{}
+		for _, g := range groupings {
+			names = append(names, g.name)
+		}
+		a := m.arrangement
+		var err error
+		a.group, err = cycle(names, a.group, args)
+		m.rearrange(a)
+		return err
+	}},
+	{"flatten", []string{"F"}, "show the tree flat, or as a tree again", "", func(m *app, _ string) error {
+		a := m.arrangement
+		a.flat = !a.flat
+		m.rearrange(a)
+		return nil
+	}},
+	{"tab", nil, "show the tab with a name or number", "<tab>", func(m *app, args string) error {
+		i, err := m.findTab(args)
+		if err != nil {
+			return err
+		}
+		m.showTab(i)
+		return nil
+	}},
+	{"tab-new", nil, "add a tab like this one", "[name]", func(m *app, args string) error {
+		if args == "" {
+			args = "Tab " + strconv.Itoa(len(m.all.Views)+1)
+		}
+		m.saveTab()
+		m.all.AddView(m.tabs.Value()+1, args)
+		m.renameTabs()
+		m.showTab(m.tabs.Value() + 1)
+		return nil
+	}},
+	{"tab-rename", nil, "rename the tab", "<name>", func(m *app, args string) error {
+		if args == "" {
+			return badArgs("what name?")
+		}
+		m.tab().Name = args
+		m.renameTabs()
+		m.updateVisible()
+		return nil
+	}},
+	{"tab-delete", nil, "delete the tab", "", func(m *app, _ string) error {
+		if err := m.all.RemoveView(m.tabs.Value()); err != nil {
+			return badArgs(err.Error())
+		}
+		m.renameTabs()
+		m.loadTab()
+		m.showTab(m.tabs.Value())
+		return nil
+	}},
+	{"tab-left", nil, "move the tab left", "", func(m *app, _ string) error {
+		m.moveTab(-1)
+		return nil
+	}},
+	{"tab-right", nil, "move the tab right", "", func(m *app, _ string) error {
+		m.moveTab(1)
+		return nil
+	}},
+	{"filter", []string{"/"}, "change which tasks the tab shows", "[filter]", func(m *app, args string) error {
+		if args == "" {
+			m.openPalette()
+			m.textinput.SetValue("filter " + m.tab().Filter)
+			m.textinput.Width = len(m.textinput.Value()) + 1
+			m.textinput.SetCursor(m.textinput.Width)
+			return nil
+		}
+		if args == "-" {
+			args = ""
+		}
+		if _, err := parseFilter(args, time.Now()); err != nil {
+			return err
+		}
+		id := getID(m.atCursor())
+		m.tab().Filter = args
+		m.updateVisible()
+		m.setCursor(max(m.indexOf(id), 0))
+		return nil
+	}},
+	{"palette", []string{":", "ctrl+p"}, "search the actions", "", func(m *app, _ string) error {
+		m.openPalette()
+		return nil
+	}},
+	{"help", []string{"?"}, "list every key", "", func(m *app, _ string) error {
+		m.mode = helpMode
+		m.viewport.YOffset = 0
+		return nil
+	}},
+	{"hints", nil, "show or hide the keys below the status line", "", func(m *app, _ string) error {
+		m.showHints = !m.showHints
+		m.layout()
+		m.setCursor(m.cursor)
+		return nil
+	}},
+}, tabKeys()...)
+
+// keymap finds the action bound to a key
+var keymap = func() map[string]action {
+	keymap := map[string]action{}
+	for _, a := range actions {
+		for _, key := range a.keys {
+			keymap[key] = a
+		}
+	}
+	return keymap
+}()
+
+// keyName names a key the way the keymap does. bubbletea has no name for
+// delete, which would otherwise match every key it cannot name.
+func keyName(msg tea.KeyMsg) string {
+	if msg.Type == tea.KeyDelete {
+		return "delete"
+	}
+	return msg.String()
+}
+
+// anywhere reports whether a key runs an action that needs no task, which the
+// views without any let through
+func anywhere(key string) bool {
+	switch keymap[key].name {
+	case "view", "unhoist", "filter", "palette", "help", "hints":
+		return true
+	}
+	return strings.HasPrefix(keymap[key].name, "tab-")
+}
+
+// perform runs an action, warning about arguments it cannot make sense of
+func (m *app) perform(a action, args string) tea.Cmd {
+	err := a.run(m, args)
+	if errors.As(err, new(badArgs)) {
+		m.warning = err
+		return nil
+	}
+	if err != nil {
+		return report(err)
+	}
+	return nil
+}
+
+// add adds a task next to the one at the cursor, and edits its title unless
+// it is given one
+func (m *app) add(anchor task.Pos, title string) error {
+	id := getID(m.atCursor())
+	parent := m.all.Parent[id]
+	if len(parent) == 0 {
+		parent = m.root()
+	}
+	var due *time.Time
+	if m.view == agendaView {
+		// the new task is due on the same day, so that it shows up next to
+		// this one, or today in an empty agenda
+		due = m.all.Nodes[id].Due
+		if due == nil {
+			today := time.Now().Truncate(time.Hour * 24)
+			due = &today
+		}
+		if !m.keep()(task.Task{Title: title, Due: due}) {
+			return badArgs("the filter of this tab would hide the new task: " + m.tab().Filter)
+		}
+	}
+	added, err := m.all.Add(parent, id, anchor)
+	if err != nil {
+		return err
+	}
+	if due != nil {
+		err = m.all.SetDue(added, due)
+	}
+	m.updateVisible()
+	// the subtasks of the task at the cursor may be in between
+	if i := m.indexOf(added); i >= 0 {
+		m.setCursor(i)
+	} else {
+		m.setCursor(m.cursor + int(anchor))
+	}
+	if title == "" {
+		m.edit()
+		return err
+	}
+	if titleErr := m.all.SetTitle(added, title); titleErr != nil {
+		return titleErr
+	}
+	m.updateVisible()
+	return err
+}
+
+func (m *app) toggle(string) error {
+	id := getID(m.atCursor())
+	if len(id) == 0 {
+		return nil
+	}
+	now := time.Now()
+	var err error
+	if m.all.Nodes[id].Done == nil {
+		err = m.all.SetDone(id, &now)
+	} else {
+		err = m.all.SetDone(id, nil)
+	}
+	m.updateVisible()
+	return err
+}
+
+// date asks for the due or start date of the task at the cursor, or sets it
+// to the one given
+func (m *app) date(mode mode, args string) error {
+	if len(getID(m.atCursor())) == 0 {
+		return nil
+	}
+	if args == "" {
+		m.dateinput.SetValue(nil)
+		m.dateinput.Prefix = "due"
+		if mode == startMode {
+			m.dateinput.Prefix = "start"
+		}
+		m.mode = mode
+		return nil
+	}
+	var d *time.Time
+	if args != "none" {
+		if d = dateinput.Parse(strings.ToLower(args)); d == nil {
+			return badArgs(fmt.Sprintf("%q is not a date", args))
+		}
+	}
+	return m.setDate(mode, d)
+}
+
+// setDate sets the due or start date of the task at the cursor
+func (m *app) setDate(mode mode, d *time.Time) error {
+	set := m.all.SetDue
+	if mode == startMode {
+		set = m.all.SetStart
+	}
+	id := getID(m.atCursor())
+	err := set(id, d)
+	m.updateVisible()
+	// the task moves to another day in the calendar or the agenda, the cursor
+	// goes with it
+	if due := m.all.Nodes[id].Due; m.view == calendarView && due != nil {
+		m.selectDay(*due)
+		m.setCursor(max(m.indexOf(id), 0))
+	} else if i := m.indexOf(id); i >= 0 {
+		m.setCursor(i)
+	}
+	return err
+}
+
+// moveBy moves the task at the cursor past the previous or next one
+func (m *app) moveBy(by int) error {
+	if m.arranged() {
+		return errArranged
+	}
+	c := m.cursor
+	id := getID(m.atCursor())
+	if m.view == agendaView {
+		err := m.shift(id, by)
+		m.updateVisible()
+		m.setCursor(max(m.indexOf(id), 0))
+		return err
+	}
+	if !m.moveSameParent(by) {
+		return nil
+	}
+	other := getID(m.atCursor())
+	if by < 0 {
+		err := m.all.Move(other, m.all.Parent[id], id, task.Below)
+		m.updateVisible()
+		return err
+	}
+	err := m.all.Move(id, m.all.Parent[id], other, task.Below)
+	m.updateVisible()
+	m.setCursor(c)
+	m.moveSameParent(1)
+	return err
+}
+
+// indentCursor moves the task at the cursor under the one above it
+func (m *app) indentCursor(string) error {
+	if m.arranged() {
+		return errArranged
+	}
+	id := getID(m.atCursor())
+	switch {
+	case m.view == agendaView:
+		err := m.indent(id)
+		m.updateVisible()
+		return err
+	case m.view.outline():
+		c := m.cursor
+		if !m.moveSameParent(-1) {
+			return nil
+		}
+		above := getID(m.atCursor())
+		err := m.all.Move(id, above, "", task.Below)
+		m.updateVisible()
+		m.setCursor(c)
+		return err
+	}
+	return nil
+}
+
+// outdentCursor moves the task at the cursor out of its parent
+func (m *app) outdentCursor(string) error {
+	if m.arranged() {
+		return errArranged
+	}
+	id := getID(m.atCursor())
+	switch {
+	case m.view == agendaView:
+		err := m.outdent(id)
+		m.updateVisible()
+		return err
+	case m.view.outline():
+		c := m.cursor
+		if !m.moveUpLeft() {
+			return nil
+		}
+		above := getID(m.atCursor())
+		err := m.all.Move(id, m.all.Parent[above], above, 1)
+		m.updateVisible()
+		m.setCursor(c)
+		return err
+	}
+	return nil
+}
+
+// setView switches to another view
+func (m *app) setView(v view) {
+	m.view = v
+	switch m.view {
+	case boardView:
+		m.board.project = m.project()
+	case burndownView:
+		m.burndown = m.subtree()
+	}
+	m.layout()
+	m.viewport.YOffset = 0
+	if m.view == timelineView && m.timeline.from.IsZero() {
+		m.scrollTimeline()
+	}
+	m.updateVisible()
+	m.setCursor(0)
+}
+
+// find returns the task whose title matches a query best, leaving out except
+// and everything under it
+func (m app) find(query string, except task.ID) (task.ID, error) {
+	if query == "" {
+		return "", badArgs("which task?")
+	}
+	best, bestScore := task.ID(""), 0
+	for id, t := range m.all.Nodes {
+		if id == "root" || len(except) > 0 && m.under(id, except) {
+			continue
+		}
+		score, ok := fuzzy(query, t.Title)
+		// the shorter title wins a tie, then the ID so that it is always the same
+		if !ok || best != "" && (score < bestScore || score == bestScore &&
+			(len(t.Title) > len(m.all.Nodes[best].Title) || len(t.Title) == len(m.all.Nodes[best].Title) && id > best)) {
+			continue
+		}
+		best, bestScore = id, score
+	}
+	if best == "" {
+		return "", badArgs(fmt.Sprintf("no task matches %q", query))
+	}
+	return best, nil
+}
+
+// under reports whether id is ancestor or one of its descendants
+func (m app) under(id, ancestor task.ID) bool {
+	for ; id != "" && id != "root"; id = m.all.Parent[id] {
+		if id == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// export writes the tasks shown to a file in some format, or copies them
+// to the clipboard without one
+func (m *app) export(args string) error {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 || format.Codecs[fields[0]] == nil {
+		names := []string{}
+		for name := range format.Codecs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return badArgs("formats are " + strings.Join(names, ", "))
+	}
+	var b strings.Builder
+	if err := format.Codecs[fields[0]].Encode(&b, m.all, m.root()); err != nil {
+		return err
+	}
+	if len(fields) == 1 {
+		if err := clipboard.WriteAll(b.String()); err != nil {
+			return err
+		}
+		m.notice = "copied as " + fields[0]
+		return nil
+	}
+	if err := os.WriteFile(fields[1], []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	m.notice = "saved to " + fields[1]
+	return nil
+}
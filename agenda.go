@@ -0,0 +1,234 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/td0m/taskman/task"
+	"github.com/td0m/taskman/ui"
+)
+
+// view is how the tasks are laid out
+type view int
+
+const (
+	treeView view = iota
+	agendaView
+	calendarView
+	boardView
+	timelineView
+	burndownView
+	reportView
+)
+
+var viewNames = []string{"tree", "agenda", "calendar", "board", "timeline", "burndown", "report"}
+
+// grid reports whether a view lays tasks out in cells, which always fit the
+// viewport
+func (v view) grid() bool {
+	return v == calendarView || v == boardView
+}
+
+// outline reports whether a view shows the tree, where tasks are moved around
+// as in the tree view
+func (v view) outline() bool {
+	return v == treeView || v == timelineView
+}
+
+// chart reports whether a view draws the tasks rather than listing them, so
+// that there are none to act on
+func (v view) chart() bool {
+	return v == burndownView || v == reportView
+}
+
+// inline reports whether titles are edited in place rather than in the status line
+func (v view) inline() bool {
+	return v == treeView || v == agendaView
+}
+
+// dated lists the tasks with a due date that pass the current filter, in
+// tree order, ignoring folds
+func (m *app) dated() []path {
+	keep := m.keep()
+	paths := []path{}
+	var walk func(p path)
+	walk = func(p path) {
+		for _, c := range m.all.Children[getID(p)] {
+			cp := append(append(path{}, p...), c)
+			if t := m.all.Nodes[c]; t.Due != nil && keep(t) {
+				paths = append(paths, cp)
+			}
+			walk(cp)
+		}
+	}
+	walk(path{m.root()})
+	return paths
+}
+
+// agendaKeys are the keys that differ in the agenda view, which lists tasks
+// by date and arranges them by the tree they are in
+var agendaKeys = []viewBinding{
+	{binding{[]string{"K", "J"}, "reorder", "move before or after its sibling, which orders tasks due the same day"}, func(m *app, key string) error {
+		by := 1
+		if key == "K" {
+			by = -1
+		}
+		return m.moveBy(by)
+	}},
+	{binding{[]string{"tab", "shift+tab"}, "indent", "make a subtask of its previous sibling, or move out of the parent"}, func(m *app, key string) error {
+		if key == "shift+tab" {
+			return m.outdentCursor("")
+		}
+		return m.indentCursor("")
+	}},
+}
+
+// agenda lists the dated tasks by due date, and then in tree order
+func (m *app) agenda() []path {
+	today := time.Now().Truncate(time.Hour * 24)
+	paths := []path{}
+	for _, p := range m.dated() {
+		// there is nothing left to plan about tasks that were done late
+		if t := m.all.Nodes[getID(p)]; !(t.Done != nil && t.Due.Before(today)) {
+			paths = append(paths, p)
+		}
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return m.all.Nodes[getID(paths[i])].Due.Before(*m.all.Nodes[getID(paths[j])].Due)
+	})
+	return paths
+}
+
+// section is the heading of the agenda a task due on due is listed under
+func section(due, now time.Time) string {
+	today := now.Truncate(time.Hour * 24)
+	days := int(math.Floor(due.Sub(today).Hours() / 24))
+	// weeks start on monday
+	weekday := (int(today.Weekday()) + 6) % 7
+	switch {
+	case days < 0:
+		return "Overdue"
+	case days == 0:
+		return "Today"
+	case days == 1:
+		return "Tomorrow"
+	case days < 7-weekday:
+		return due.Weekday().String()
+	case days < 14-weekday:
+		return "Next week"
+	default:
+		return "Later"
+	}
+}
+
+// agendaHeader returns the heading shown above the ith task, if any
+func (m app) agendaHeader(i int) string {
+	now := time.Now()
+	s := section(*m.all.Nodes[getID(m.visible[i])].Due, now)
+	if i > 0 && section(*m.all.Nodes[getID(m.visible[i-1])].Due, now) == s {
+		return ""
+	}
+	return s
+}
+
+func (m app) agendaSizeOf(i int) int {
+	switch {
+	case m.agendaHeader(i) == "":
+		return 1
+	case i == 0:
+		return 2
+	default:
+		return 3
+	}
+}
+
+var emptyAgenda = lipgloss.NewStyle().Foreground(ui.Faded)
+
+func (m app) renderAgenda() string {
+	if len(m.visible) == 0 {
+		// such as under a tab for undated tasks, which the agenda never lists
+		if filter := m.tab().Filter; filter != "" {
+			return emptyAgenda.Render("nothing due passes the filter of this tab: " + filter)
+		}
+		return emptyAgenda.Render("nothing is due, a adds a task for today")
+	}
+	s := ""
+	for i, p := range m.visible {
+		t := m.all.Nodes[getID(p)]
+		if header := m.agendaHeader(i); header != "" {
+			if i > 0 {
+				s += "\n"
+			}
+			s += ui.RenderSection(header) + "\n"
+		}
+		s += ui.RenderIcon(t)
+		if m.mode == titleMode && i == m.cursor {
+			s += m.textinput.View()
+		} else {
+			title := ui.Title(t)
+			if i == m.cursor && m.mode == normalMode {
+				title = title.Copy().Background(ui.Faded).Foreground(ui.Background)
+			}
+			s += title.Render(t.Title)
+		}
+		// the path of ancestors, as the tree is not there to tell
+		ancestors := []string{}
+		for _, id := range p[1 : len(p)-1] {
+			ancestors = append(ancestors, m.all.Nodes[id].Title)
+		}
+		if len(ancestors) > 0 {
+			s += ui.RenderPath(strings.Join(ancestors, " › "))
+		}
+		if section(*t.Due, time.Now()) == "Later" || section(*t.Due, time.Now()) == "Next week" {
+			s += ui.RenderDue(t)
+		}
+		s += "\n"
+	}
+	return s
+}
+
+// indent moves a task under its previous sibling, going by the tree rather
+// than by what is on screen
+func (m *app) indent(id task.ID) error {
+	parent := m.all.Parent[id]
+	siblings := m.all.Children[parent]
+	for i, c := range siblings {
+		if c == id && i > 0 {
+			return m.all.Move(id, siblings[i-1], "", task.Below)
+		}
+	}
+	return nil
+}
+
+// outdent moves a task out of its parent, right below it
+func (m *app) outdent(id task.ID) error {
+	parent := m.all.Parent[id]
+	if parent == "root" || parent == "" {
+		return nil
+	}
+	return m.all.Move(id, m.all.Parent[parent], parent, task.Below)
+}
+
+// shift moves a task up or down among its siblings
+func (m *app) shift(id task.ID, by int) error {
+	parent := m.all.Parent[id]
+	siblings := m.all.Children[parent]
+	for i, c := range siblings {
+		if c != id {
+			continue
+		}
+		j := i + by
+		if j < 0 || j >= len(siblings) {
+			return nil
+		}
+		pos := task.Below
+		if by < 0 {
+			pos = task.Above
+		}
+		return m.all.Move(id, parent, siblings[j], pos)
+	}
+	return nil
+}
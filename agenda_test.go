@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+func TestSection(t *testing.T) {
+	// a thursday
+	now := time.Date(2026, 10, 22, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		days int
+		want string
+	}{
+		{-1, "Overdue"},
+		{0, "Today"},
+		{1, "Tomorrow"},
+		{2, "Saturday"},
+		{3, "Sunday"},
+		{4, "Next week"},
+		{10, "Next week"},
+		{11, "Later"},
+	}
+	for _, tt := range tests {
+		due := time.Date(2026, 10, 22+tt.days, 0, 0, 0, 0, time.UTC)
+		if got := section(due, now); got != tt.want {
+			t.Errorf("due in %d days: got %q, want %q", tt.days, got, tt.want)
+		}
+	}
+}
+
+func TestAgendaAdd(t *testing.T) {
+	store := storage.NewJSON(filepath.Join(t.TempDir(), "tasks.json"))
+	tasks, _ := store.Fetch()
+	m := newApp(store, tasks)
+	m.setView(agendaView)
+
+	// with nothing due, the new task is due today
+	if err := m.add(task.Below, ""); err != nil {
+		t.Fatal(err)
+	}
+	id := getID(m.atCursor())
+	today := time.Now().Truncate(time.Hour * 24)
+	if due := m.all.Nodes[id].Due; due == nil || !due.Equal(today) {
+		t.Errorf("due %v, want %v", due, today)
+	}
+	if m.mode != titleMode {
+		t.Errorf("the title of the new task is not being edited")
+	}
+
+	// the Inbox lists undated tasks, none of which are in the agenda
+	m.mode = normalMode
+	m.tabs.Set(1)
+	m.updateVisible()
+	if err := m.add(task.Below, "hidden"); !errors.As(err, new(badArgs)) {
+		t.Errorf("got %v, want a warning", err)
+	}
+	if len(m.all.Nodes) != 2 {
+		t.Errorf("got %d tasks, want the root and the first one", len(m.all.Nodes))
+	}
+}
@@ -0,0 +1,553 @@
+// Package api serves the task list as a REST JSON API, reading and writing the
+// same task file as the TUI.
+//
+//	GET    /tasks             list tasks, filtered by ?parent= ?done= ?tag= ?q= ?due_before=
+//	POST   /tasks             add a task, placed by parent, anchor and pos
+//	GET    /tasks/{id}        get a task
+//	PATCH  /tasks/{id}        update the given fields of a task
+//	DELETE /tasks/{id}        delete a task and its subtasks
+//	POST   /tasks/{id}/toggle mark a task done, or not done
+//	POST   /tasks/{id}/move   move a task to a parent, anchor and pos
+//
+// Every task is returned with an ETag. Sending it back in If-Match makes a
+// change fail with 412 Precondition Failed if the task changed in the meantime.
+//
+// Writes take JSON bodies only, sent with Content-Type application/json.
+// Requests from pages other than AllowOrigin, and to hosts other than Hosts,
+// are refused with 403 Forbidden, so that a page open in a browser cannot
+// change the tasks behind the user's back.
+//
+// A TUI open on the file keeps a copy of the tasks and writes it over the file,
+// so changes are handed to it through Forward rather than written. A TUI that
+// could not open its control socket is out of reach, and overwrites them.
+package api
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/td0m/taskman/remind"
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+// Server handles API requests. Each one reads the task file, and writes it
+// back if anything changed.
+type Server struct {
+	store *storage.JSONBackend
+	// AllowOrigin is sent as Access-Control-Allow-Origin, for pages served
+	// from elsewhere. Requests from any other page are refused.
+	AllowOrigin string
+	// Hosts, if set, are the only Host headers accepted, which keeps out pages
+	// that get at the server by rebinding a name of their own to its address
+	Hosts []string
+	// Forward, if set, hands the tasks after a change over to whatever has the
+	// file open, to replace the copy it has of the tasks before. It fails with
+	// ErrNotForwarded when nothing does, and the file is written instead.
+	Forward func(before, after task.Tasks) error
+
+	mu sync.Mutex
+}
+
+func New(store *storage.JSONBackend) *Server {
+	return &Server{store: store}
+}
+
+// Item is a task as returned by the API
+type Item struct {
+	ID       task.ID   `json:"id"`
+	Parent   task.ID   `json:"parent"`
+	Children []task.ID `json:"children"`
+	task.Task
+}
+
+// Placement is where a task is added or moved to, as in task.Tasks.Move
+type Placement struct {
+	Parent task.ID `json:"parent"`
+	Anchor task.ID `json:"anchor"`
+	// Pos is "above" or "below" the anchor, below by default
+	Pos string `json:"pos"`
+}
+
+// Patch holds the fields to update. Fields left out are not changed, and due
+// and start are cleared with null.
+type Patch struct {
+	Title     *string         `json:"title"`
+	Due       json.RawMessage `json:"due"`
+	Start     json.RawMessage `json:"start"`
+	Done      *bool           `json:"done"`
+	Priority  *string         `json:"priority"`
+	Tags      *[]string       `json:"tags"`
+	Reminders *[]string       `json:"reminders"`
+	Folded    *bool           `json:"folded"`
+	// Status is one of the workflow, and wins over Done
+	Status *string `json:"status"`
+}
+
+var (
+	errNotFound     = errors.New("not found")
+	errMethod       = errors.New("method not allowed")
+	errPrecondition = errors.New("task was changed, fetch it again")
+	errBadPos       = errors.New(`pos must be "above" or "below"`)
+	errBadPriority  = errors.New("priority must be a letter from A to Z")
+	errBadDue       = errors.New("due must be a date such as 2026-10-20")
+	errBadStart     = errors.New("start must be a date such as 2026-10-20")
+	errNoTitle      = errors.New("title is required")
+	errOrigin       = errors.New("requests from this origin are not allowed")
+	errHost         = errors.New("unknown host")
+	errContentType  = errors.New("content type must be application/json")
+)
+
+var (
+	// ErrNotForwarded is returned by Forward when nothing has the file open
+	ErrNotForwarded = errors.New("nothing to forward to")
+	// ErrStale is returned by Forward when the tasks were changed where they
+	// were forwarded to since they were read
+	ErrStale = errors.New("tasks were changed in the meantime, try again")
+)
+
+// statusError sets the HTTP status an error is reported with
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e statusError) Error() string { return e.err.Error() }
+func (e statusError) Unwrap() error { return e.err }
+
+// save forwards the change from before to after, or writes it to the file if
+// there is nothing to forward it to
+func (s *Server) save(before, after task.Tasks) error {
+	err := ErrNotForwarded
+	if s.Forward != nil {
+		err = s.Forward(before, after)
+	}
+	switch {
+	case errors.Is(err, ErrNotForwarded):
+		_, err = s.store.Sync(after)
+	case errors.Is(err, ErrStale):
+		err = statusError{http.StatusConflict, err}
+	}
+	return err
+}
+
+// check refuses requests from pages other than AllowOrigin, to hosts other
+// than Hosts, and with bodies other than JSON
+func (s *Server) check(r *http.Request) error {
+	if origin := r.Header.Get("Origin"); origin != "" && origin != s.AllowOrigin {
+		return statusError{http.StatusForbidden, errOrigin}
+	}
+	if len(s.Hosts) > 0 {
+		allowed := false
+		for _, h := range s.Hosts {
+			allowed = allowed || strings.EqualFold(h, r.Host)
+		}
+		if !allowed {
+			return statusError{http.StatusForbidden, errHost}
+		}
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodOptions {
+		return nil
+	}
+	media := ""
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		media, _, _ = mime.ParseMediaType(ct)
+	}
+	if media != "application/json" && (media != "" || r.ContentLength != 0) {
+		return statusError{http.StatusUnsupportedMediaType, errContentType}
+	}
+	return nil
+}
+
+// after runs the hooks for after the changes of a request once they are
+// saved, and forgets them otherwise. They run in the background, as the
+// response does not wait for them, and their failures go unreported.
+func (s *Server) after(saved bool) {
+	if s.store.After == nil {
+		return
+	}
+	if after := s.store.After(); after != nil && saved {
+		go after()
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// a page can send a form anywhere without asking, so those from elsewhere
+	// are turned away, and bodies have to be JSON, which forms cannot send
+	if err := s.check(r); err != nil {
+		fail(w, err)
+		return
+	}
+	if s.AllowOrigin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", s.AllowOrigin)
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Location")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	// /tasks, /tasks/{id} or /tasks/{id}/{action}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "tasks" || len(parts) > 3 {
+		fail(w, statusError{http.StatusNotFound, errNotFound})
+		return
+	}
+	var id task.ID
+	action := ""
+	if len(parts) > 1 {
+		id = task.ID(parts[1])
+	}
+	if len(parts) > 2 {
+		action = parts[2]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tasks, err := s.store.Fetch()
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	before := tasks.Clone()
+
+	var (
+		status  = http.StatusOK
+		changed = true
+		res     interface{}
+	)
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		res, err = list(tasks, r)
+		changed = false
+	case id == "" && r.Method == http.MethodPost:
+		var item Item
+		item, err = add(&tasks, r)
+		res, status = item, http.StatusCreated
+		w.Header().Set("Location", "/tasks/"+string(item.ID))
+	case id == "root" || !exists(tasks, id):
+		err = statusError{http.StatusNotFound, task.ErrBadID}
+	case action == "" && r.Method == http.MethodGet:
+		res, changed = itemOf(tasks, id), false
+	case r.Header.Get("If-Match") != "" && !matches(r.Header.Get("If-Match"), etagOf(itemOf(tasks, id))):
+		err = statusError{http.StatusPreconditionFailed, errPrecondition}
+	case action == "" && r.Method == http.MethodPatch:
+		err = patch(&tasks, id, r)
+	case action == "" && r.Method == http.MethodDelete:
+		err, status = tasks.Remove(id), http.StatusNoContent
+	case action == "toggle" && r.Method == http.MethodPost:
+		err = toggle(&tasks, id)
+	case action == "move" && r.Method == http.MethodPost:
+		err = move(&tasks, id, r)
+	case action == "" || action == "toggle" || action == "move":
+		err = statusError{http.StatusMethodNotAllowed, errMethod}
+	default:
+		err = statusError{http.StatusNotFound, errNotFound}
+	}
+	if err != nil {
+		s.after(false)
+		fail(w, err)
+		return
+	}
+
+	if changed {
+		err := s.save(before, tasks)
+		s.after(err == nil)
+		if err != nil {
+			fail(w, err)
+			return
+		}
+		if res == nil && status != http.StatusNoContent {
+			res = itemOf(tasks, id)
+		}
+	}
+	if item, ok := res.(Item); ok {
+		w.Header().Set("ETag", etagOf(item))
+	}
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
+
+// list returns the tasks under ?parent= in tree order, keeping the ones that
+// match every filter given
+func list(tasks task.Tasks, r *http.Request) ([]Item, error) {
+	q := r.URL.Query()
+	parent := task.ID(q.Get("parent"))
+	if parent == "" {
+		parent = "root"
+	}
+	var dueBefore *time.Time
+	if s := q.Get("due_before"); s != "" {
+		d, err := parseDue(s)
+		if err != nil {
+			return nil, err
+		}
+		dueBefore = d
+	}
+	keep := func(t task.Task) bool {
+		switch {
+		case q.Get("done") == "true" && t.Done == nil,
+			q.Get("done") == "false" && t.Done != nil,
+			q.Get("tag") != "" && !contains(t.Tags, q.Get("tag")),
+			q.Get("q") != "" && !strings.Contains(strings.ToLower(t.Title), strings.ToLower(q.Get("q"))),
+			dueBefore != nil && (t.Due == nil || !t.Due.Before(*dueBefore)):
+			return false
+		}
+		return true
+	}
+
+	items := []Item{}
+	var walk func(id task.ID)
+	walk = func(id task.ID) {
+		for _, c := range tasks.Children[id] {
+			if keep(tasks.Nodes[c]) {
+				items = append(items, itemOf(tasks, c))
+			}
+			walk(c)
+		}
+	}
+	walk(parent)
+	return items, nil
+}
+
+func add(tasks *task.Tasks, r *http.Request) (Item, error) {
+	var body struct {
+		Placement
+		Patch
+	}
+	if err := decode(r, &body); err != nil {
+		return Item{}, err
+	}
+	if body.Title == nil || strings.TrimSpace(*body.Title) == "" {
+		return Item{}, statusError{http.StatusBadRequest, errNoTitle}
+	}
+	parent, anchor, pos, err := placement(body.Placement)
+	if err != nil {
+		return Item{}, err
+	}
+	id, err := tasks.Add(parent, anchor, pos)
+	if err != nil {
+		return Item{}, err
+	}
+	if err := apply(tasks, id, body.Patch); err != nil {
+		tasks.Remove(id)
+		return Item{}, err
+	}
+	return itemOf(*tasks, id), nil
+}
+
+func patch(tasks *task.Tasks, id task.ID, r *http.Request) error {
+	var p Patch
+	if err := decode(r, &p); err != nil {
+		return err
+	}
+	return apply(tasks, id, p)
+}
+
+// apply validates every field of p before changing any of them
+func apply(tasks *task.Tasks, id task.ID, p Patch) error {
+	t := tasks.Nodes[id]
+	if p.Title != nil {
+		t.Title = *p.Title
+	}
+	if p.Due != nil {
+		due, err := parseDate(p.Due, errBadDue)
+		if err != nil {
+			return err
+		}
+		t.Due = due
+	}
+	if p.Start != nil {
+		start, err := parseDate(p.Start, errBadStart)
+		if err != nil {
+			return err
+		}
+		t.Start = start
+	}
+	if p.Priority != nil {
+		if len(*p.Priority) > 1 || *p.Priority != "" && (*p.Priority < "A" || *p.Priority > "Z") {
+			return statusError{http.StatusBadRequest, errBadPriority}
+		}
+		t.Priority = *p.Priority
+	}
+	if p.Tags != nil {
+		t.Tags = *p.Tags
+	}
+	if p.Reminders != nil {
+		if err := remind.Validate(*p.Reminders); err != nil {
+			return statusError{http.StatusBadRequest, err}
+		}
+		t.Reminders = *p.Reminders
+	}
+	if p.Folded != nil {
+		t.Folded = *p.Folded
+	}
+	if p.Status != nil && !known(tasks.Workflow(), *p.Status) {
+		return statusError{http.StatusBadRequest, task.ErrBadStatus}
+	}
+	if err := tasks.Set(id, t); err != nil {
+		return err
+	}
+
+	// the status goes last, as it also decides whether the task is done
+	if p.Status != nil {
+		return tasks.SetStatus(id, *p.Status)
+	}
+
+	if p.Done != nil && *p.Done != (tasks.Nodes[id].Done != nil) {
+		return toggle(tasks, id)
+	}
+	return nil
+}
+
+func known(workflow []string, status string) bool {
+	for _, s := range workflow {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// toggle marks a task done with its subtasks, as the TUI does
+func toggle(tasks *task.Tasks, id task.ID) error {
+	if tasks.Nodes[id].Done != nil {
+		return tasks.SetDone(id, nil)
+	}
+	now := time.Now()
+	return tasks.SetDone(id, &now)
+}
+
+func move(tasks *task.Tasks, id task.ID, r *http.Request) error {
+	var p Placement
+	if err := decode(r, &p); err != nil {
+		return err
+	}
+	parent, anchor, pos, err := placement(p)
+	if err != nil {
+		return err
+	}
+	return tasks.Move(id, parent, anchor, pos)
+}
+
+func placement(p Placement) (parent, anchor task.ID, pos task.Pos, err error) {
+	parent = p.Parent
+	if parent == "" {
+		parent = "root"
+	}
+	switch p.Pos {
+	case "above":
+		pos = task.Above
+	case "below", "":
+		pos = task.Below
+	default:
+		err = statusError{http.StatusBadRequest, errBadPos}
+	}
+	return parent, p.Anchor, pos, err
+}
+
+func decode(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return statusError{http.StatusBadRequest, err}
+	}
+	return nil
+}
+
+// parseDue reads a date, kept as midnight UTC like the dates of imported tasks
+func parseDue(s string) (*time.Time, error) {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, statusError{http.StatusBadRequest, errBadDue}
+	}
+	return &d, nil
+}
+
+// parseDate reads a date of a task, which is null to clear it, failing with bad
+func parseDate(raw json.RawMessage, bad error) (*time.Time, error) {
+	var s *string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, statusError{http.StatusBadRequest, bad}
+	}
+	if s == nil {
+		return nil, nil
+	}
+	d, err := time.Parse("2006-01-02", *s)
+	if err != nil {
+		return nil, statusError{http.StatusBadRequest, bad}
+	}
+	return &d, nil
+}
+
+func itemOf(tasks task.Tasks, id task.ID) Item {
+	children := tasks.Children[id]
+	if children == nil {
+		children = []task.ID{}
+	}
+	return Item{id, tasks.Parent[id], children, tasks.Nodes[id]}
+}
+
+// etagOf identifies the current version of a task, including where it is
+func etagOf(item Item) string {
+	data, _ := json.Marshal(item)
+	sum := sha1.Sum(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// matches reports whether an If-Match header lists etag
+func matches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func exists(tasks task.Tasks, id task.ID) bool {
+	_, found := tasks.Nodes[id]
+	return found
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func fail(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var se statusError
+	switch {
+	case errors.As(err, &se):
+		status = se.status
+	case errors.Is(err, task.ErrBadID):
+		status = http.StatusNotFound
+	case errors.Is(err, task.ErrNoParent), errors.Is(err, task.ErrCycle):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, task.ErrRejected):
+		status = http.StatusConflict
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
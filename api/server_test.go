@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+type client struct {
+	t      *testing.T
+	url    string
+	server *Server
+}
+
+func newClient(t *testing.T) *client {
+	store := storage.NewJSON(filepath.Join(t.TempDir(), "tasks.json"))
+	server := New(store)
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	return &client{t, ts.URL, server}
+}
+
+// do sends a request, checks its status and decodes the response into v
+func (c *client) do(method, path, body string, header http.Header, status int, v interface{}) http.Header {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.url+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != status {
+		var e map[string]string
+		json.NewDecoder(res.Body).Decode(&e)
+		c.t.Fatalf("%s %s: got %d %s, want %d", method, path, res.StatusCode, e["error"], status)
+	}
+	if v != nil {
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	return res.Header
+}
+
+func TestServer(t *testing.T) {
+	c := newClient(t)
+
+	var a, b, sub Item
+	c.do("POST", "/tasks", `{"title":"a","due":"2026-10-20","tags":["work"]}`, nil, http.StatusCreated, &a)
+	c.do("POST", "/tasks", `{"title":"b","anchor":"`+string(a.ID)+`","pos":"above"}`, nil, http.StatusCreated, &b)
+	c.do("POST", "/tasks", `{"title":"sub","parent":"`+string(a.ID)+`"}`, nil, http.StatusCreated, &sub)
+	c.do("POST", "/tasks", `{"title":"bad","parent":"missing"}`, nil, http.StatusUnprocessableEntity, nil)
+	c.do("POST", "/tasks", `{"title":"bad","priority":"high"}`, nil, http.StatusBadRequest, nil)
+
+	var items []Item
+	c.do("GET", "/tasks", "", nil, http.StatusOK, &items)
+	if len(items) != 3 || items[0].ID != b.ID || items[1].ID != a.ID || items[2].ID != sub.ID {
+		t.Fatalf("got %+v", items)
+	}
+	c.do("GET", "/tasks?tag=work&due_before=2026-10-21", "", nil, http.StatusOK, &items)
+	if len(items) != 1 || items[0].ID != a.ID {
+		t.Errorf("filtered %+v", items)
+	}
+
+	// toggling a parent marks its subtasks done too
+	c.do("POST", "/tasks/"+string(a.ID)+"/toggle", "", nil, http.StatusOK, &a)
+	c.do("GET", "/tasks?done=true", "", nil, http.StatusOK, &items)
+	if a.Done == nil || len(items) != 2 {
+		t.Errorf("toggled %+v, done %+v", a, items)
+	}
+
+	// optimistic concurrency
+	etag := c.do("GET", "/tasks/"+string(b.ID), "", nil, http.StatusOK, nil).Get("ETag")
+	ifMatch := http.Header{"If-Match": {etag}}
+	c.do("PATCH", "/tasks/"+string(b.ID), `{"title":"b2","due":null}`, ifMatch, http.StatusOK, &b)
+	c.do("PATCH", "/tasks/"+string(b.ID), `{"title":"b3"}`, ifMatch, http.StatusPreconditionFailed, nil)
+	if b.Title != "b2" {
+		t.Errorf("patched %+v", b)
+	}
+	c.do("PATCH", "/tasks/"+string(b.ID), `{"start":"2026-10-15","due":"2026-10-20"}`, nil, http.StatusOK, &b)
+	c.do("PATCH", "/tasks/"+string(b.ID), `{"start":"soon"}`, nil, http.StatusBadRequest, nil)
+	if b.Start == nil || b.Start.Day() != 15 {
+		t.Errorf("started %+v", b)
+	}
+	c.do("PATCH", "/tasks/"+string(b.ID), `{"status":"done"}`, nil, http.StatusOK, &b)
+	c.do("PATCH", "/tasks/"+string(b.ID), `{"status":"blocked"}`, nil, http.StatusBadRequest, nil)
+	if b.Status != "done" || b.Done == nil {
+		t.Errorf("moved to done %+v", b)
+	}
+
+	c.do("POST", "/tasks/"+string(a.ID)+"/move", `{"parent":"`+string(sub.ID)+`"}`, nil, http.StatusUnprocessableEntity, nil)
+	c.do("POST", "/tasks/"+string(sub.ID)+"/move", `{"anchor":"`+string(b.ID)+`","pos":"above"}`, nil, http.StatusOK, &sub)
+	if sub.Parent != "root" {
+		t.Errorf("moved under %q", sub.Parent)
+	}
+
+	c.do("DELETE", "/tasks/"+string(a.ID), "", nil, http.StatusNoContent, nil)
+	c.do("GET", "/tasks/"+string(a.ID), "", nil, http.StatusNotFound, nil)
+	c.do("GET", "/tasks", "", nil, http.StatusOK, &items)
+	if len(items) != 2 || items[0].ID != sub.ID || items[1].ID != b.ID {
+		t.Errorf("left %+v", items)
+	}
+}
+
+func TestServer_Refuses(t *testing.T) {
+	c := newClient(t)
+	c.server.AllowOrigin = "http://localhost:3000"
+	c.server.Hosts = []string{strings.TrimPrefix(c.url, "http://")}
+
+	item := `{"title":"a"}`
+	c.do("POST", "/tasks", item, http.Header{"Content-Type": {"text/plain"}}, http.StatusUnsupportedMediaType, nil)
+	c.do("POST", "/tasks", item, http.Header{"Content-Type": nil}, http.StatusUnsupportedMediaType, nil)
+	c.do("POST", "/tasks", item, http.Header{"Origin": {"http://evil.example"}}, http.StatusForbidden, nil)
+	c.do("GET", "/tasks", "", http.Header{"Origin": {"http://evil.example"}}, http.StatusForbidden, nil)
+	c.do("POST", "/tasks", item, http.Header{"Origin": {"http://localhost:3000"}}, http.StatusCreated, nil)
+
+	c.server.Hosts = []string{"localhost:1"}
+	c.do("GET", "/tasks", "", nil, http.StatusForbidden, nil)
+}
+
+func TestServer_Forward(t *testing.T) {
+	store := storage.NewJSON(filepath.Join(t.TempDir(), "tasks.json"))
+	server := New(store)
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	c := &client{t, ts.URL, server}
+
+	// stands in for a TUI with its own copy of the tasks
+	var open *task.Tasks
+	server.Forward = func(before, after task.Tasks) error {
+		switch {
+		case open == nil:
+			return ErrNotForwarded
+		case storage.Hash(*open) != storage.Hash(before):
+			return ErrStale
+		}
+		*open = after
+		return nil
+	}
+
+	var a Item
+	c.do("POST", "/tasks", `{"title":"a"}`, nil, http.StatusCreated, &a)
+	saved, _ := store.Fetch()
+	if saved.Nodes[a.ID].Title != "a" {
+		t.Fatalf("not written to the file with nothing open on it")
+	}
+
+	open = &saved
+	c.do("PATCH", "/tasks/"+string(a.ID), `{"title":"b"}`, nil, http.StatusOK, nil)
+	if open.Nodes[a.ID].Title != "b" {
+		t.Errorf("not forwarded: %+v", open.Nodes[a.ID])
+	}
+	if saved, _ := store.Fetch(); saved.Nodes[a.ID].Title != "a" {
+		t.Errorf("written to the file as well as forwarded")
+	}
+
+	// what is open has changed since the file was written
+	c.do("PATCH", "/tasks/"+string(a.ID), `{"title":"c"}`, nil, http.StatusConflict, nil)
+}
@@ -1,16 +1,22 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/td0m/taskman/api"
+	"github.com/td0m/taskman/format"
 	"github.com/td0m/taskman/pkg/dateinput"
+	"github.com/td0m/taskman/remind"
+	"github.com/td0m/taskman/stats"
 	"github.com/td0m/taskman/storage"
 	"github.com/td0m/taskman/task"
 	"github.com/td0m/taskman/ui"
@@ -27,8 +33,17 @@ const (
 	normalMode mode = iota
 	titleMode
 	dateMode
+	startMode
+	remindMode
+	paletteMode
+	helpMode
 )
 
+// editing reports whether a mode edits the task at the cursor
+func (md mode) editing() bool {
+	return md == titleMode || md == dateMode || md == startMode || md == remindMode
+}
+
 type path []task.ID
 
 type predicate func(task.Task) bool
@@ -37,111 +52,249 @@ type app struct {
 	viewport  viewport.Model
 	dateinput dateinput.Model
 	textinput textinput.Model
-
-	tabs       ui.Tabs
-	predicates []predicate
-
-	mode mode
+	// height is the height of the terminal
+	height int
+
+	// tabs are the saved views of the tree, see task.View
+	tabs ui.Tabs
+
+	mode     mode
+	view     view
+	calendar calendar
+	board    board
+	timeline timeline
+	// pressed is whether the left mouse button is held down
+	pressed bool
+	// burndown is the task the burndown view is drawn for
+	burndown task.ID
+	palette  palette
+	// arrangement orders and groups the tree view
+	arrangement arrangement
+	// showHints shows the keys worth knowing below the status line
+	showHints bool
+	// bucket is the length of the periods in the report view
+	bucket stats.Bucket
+
+	// hoisted is the task whose subtasks are shown as if they were top level
+	hoisted task.ID
 
 	all     task.Tasks
 	storage *storage.JSONBackend
 
 	visible []path
 	cursor  int
+	// headings are those of the groups the visible tasks start, if grouped
+	headings []string
+
+	// err is the last failure, shown in place of the status line until dismissed.
+	// While it is set, nothing gets written over the task file.
+	err error
+	// unsaved is set once not even an emergency copy could be saved, after
+	// which quitting loses the changes
+	unsaved bool
+	// warning is a change a hook rejected, shown until the next key press
+	warning error
+	// notice is shown in the status line until the next key press
+	notice string
+	// after are the after- hooks for changes saved during this update, run
+	// once it is done
+	after []func() error
+
+	// syncer is nil unless CalDAV sync has been configured
+	syncer     *syncer
+	syncStatus string
+
+	// reminder is nil unless notifications have been configured
+	reminder *reminder
+
+	// control is nil in tests and when the socket could not be opened
+	control *control
+}
+
+// errMsg reports a failure back into the update loop
+type errMsg struct{ err error }
+
+func report(err error) tea.Cmd {
+	return func() tea.Msg {
+		return errMsg{err}
+	}
 }
 
 // newApp creates a new taskman TUI app
-func newApp() app {
+func newApp(store *storage.JSONBackend, data task.Tasks) app {
 	ti := textinput.NewModel()
 	ti.Focus()
 	ti.Prompt = ""
 	ti.BackgroundColor = "#555"
 	ti.TextColor = "#000"
 
-	store := storage.NewJSON("./tasks.json")
-	data, err := store.Fetch()
-	if err != nil {
-		panic(err)
-	}
-
-	today := time.Now().Truncate(time.Hour * 24)
-	tomorrow := today.Add(time.Hour * 24)
-	yday := today.Add(-time.Hour * 24)
-
-	all := func(t task.Task) bool {
-		return t.Done == nil || t.Done.After(today.Add(-time.Hour*24*5))
-	}
-	inbox := func(t task.Task) bool {
-		return t.Due == nil
-	}
-	todayF := func(t task.Task) bool {
-		return (t.Done == nil || t.Done.After(yday)) && (t.Due != nil && t.Due.Before(tomorrow))
+	if len(data.Views) == 0 {
+		data.Views = task.DefaultViews()
 	}
 
-	return app{
-		all:        data,
-		storage:    store,
-		viewport:   viewport.Model{},
-		textinput:  ti,
-		dateinput:  dateinput.NewModel(),
-		tabs:       ui.NewTabs([]string{"All", "Inbox", "Today"}),
-		predicates: []predicate{all, inbox, todayF},
+	m := app{
+		all:       data,
+		storage:   store,
+		viewport:  viewport.Model{},
+		textinput: ti,
+		dateinput: dateinput.NewModel(),
+		calendar:  calendar{day: dayOf(time.Now())},
+		bucket:    stats.Week,
+		showHints: true,
 	}
+	m.renameTabs()
+	m.loadTab()
+	return m
 }
 
 // Init is the first function that will be called. It returns an optional
 // initial command. To not perform an initial command return nil.
 func (m app) Init() tea.Cmd {
-	return nil
+	cmds := []tea.Cmd{}
+	if m.syncer != nil {
+		cmds = append(cmds, m.syncer.sync(m.all))
+	}
+	if m.control != nil {
+		cmds = append(cmds, m.control.wait())
+	}
+	if m.reminder != nil {
+		cmds = append(cmds, m.reminder.check(m.all))
+	}
+	return tea.Batch(cmds...)
 }
 
 // Update is called when a message is received. Use it to inspect messages
 // and, in response, update the model and/or send a command.
-func (m app) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+func (m app) Update(msg tea.Msg) (model tea.Model, cmd tea.Cmd) {
+	// keep the terminal usable and the tasks safe if anything goes wrong
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("unexpected failure: %v", r)
+			model, cmd = m, report(err)
+			// the request still gets an answer, and the socket the next one
+			if msg, ok := msg.(controlMsg); ok {
+				select {
+				case msg.reply <- controlReply{Error: err.Error()}:
+				default:
+				}
+				cmd = tea.Batch(cmd, m.control.wait())
+			}
+		}
+	}()
+	return m.update(msg)
+}
+
+func (m app) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var (
 		cmd  tea.Cmd
 		cmds []tea.Cmd
 	)
 	switch msg := msg.(type) {
+	case errMsg:
+		if errors.Is(msg.err, task.ErrRejected) {
+			m.warning = msg.err
+			break
+		}
+		m.fail(msg.err)
+	case hooksDoneMsg:
+		if msg.err != nil {
+			m.warning = fmt.Errorf("after the change: %w", msg.err)
+		}
+	case syncTickMsg:
+		return m, m.syncer.sync(m.all)
+	case syncDoneMsg:
+		if msg.err != nil {
+			// what was pushed before it failed is not pushed again as new
+			if msg.res.State.Tasks != nil {
+				if err := msg.res.State.Save(m.syncer.state); err != nil {
+					cmds = append(cmds, report(err))
+				}
+			}
+			// most likely offline, which is not worth a banner
+			m.syncStatus = "sync failed"
+			m.updateVisible()
+			cmds = append(cmds, m.syncer.next())
+			break
+		}
+		state := msg.res.Apply(&m.all)
+		m.syncStatus = msg.res.String()
+		m.updateVisible()
+		m.setCursor(m.cursor)
+		// only remember what was synced once the tasks themselves have been saved
+		if m.err == nil {
+			if err := state.Save(m.syncer.state); err != nil {
+				cmds = append(cmds, report(err))
+			}
+		}
+		cmds = append(cmds, m.syncer.next())
+	case remindTickMsg:
+		return m, m.reminder.check(m.all)
+	case remindDoneMsg:
+		if msg.err != nil {
+			m.warning = msg.err
+		}
+		if len(msg.alerts) > 0 {
+			a := msg.alerts[0]
+			m.notice = "⏰ " + a.Task.Title + ": " + a.Message(time.Now())
+			if len(msg.alerts) > 1 {
+				m.notice += fmt.Sprintf(" (and %d more)", len(msg.alerts)-1)
+			}
+		}
+		cmds = append(cmds, m.reminder.next())
+	case controlMsg:
+		id, err := m.handle(msg.req)
+		reply := controlReply{ID: id}
+		if err != nil {
+			reply.Error = err.Error()
+		}
+		msg.reply <- reply
+		cmds = append(cmds, m.control.wait())
+	case tea.MouseMsg:
+		if err := m.mouse(msg); err != nil {
+			cmds = append(cmds, report(err))
+		}
 	case tea.WindowSizeMsg:
-		verticalMargins := headerHeight + footerHeight
+		// the cursor is put back where it was left the first time round
+		first := m.height == 0
+		m.height = msg.Height
 		m.viewport.Width = msg.Width
-		m.viewport.Height = msg.Height - verticalMargins
+		m.layout()
 		m.tabs.Width = msg.Width
 		// on init:
 		m.updateVisible()
+		if first {
+			m.cursor = max(m.indexOf(m.tab().Cursor), 0)
+		}
 		m.setCursor(m.cursor)
 	case tea.KeyMsg:
+		m.warning, m.notice = nil, ""
 		if msg.Type == tea.KeyCtrlC {
+			// nothing has been written over the task file since the failure,
+			// so whatever changed since goes to a fresh emergency copy
+			if m.err != nil {
+				if _, err := m.storage.Emergency(m.all); err != nil && !m.unsaved {
+					m.unsaved = true
+					m.err = fmt.Errorf("could not save a copy either: %v (ctrl+c again to quit anyway)", err)
+					return m, nil
+				}
+				return m, tea.Quit
+			}
 			_, err := m.storage.Sync(m.all)
 			if err != nil {
-				fmt.Println(err)
-			} else {
-				return m, tea.Quit
+				return m, report(err)
 			}
+			return m, tea.Quit
 		}
 		if msg.Type == tea.KeyEsc {
+			if m.mode == helpMode {
+				m.setCursor(m.cursor)
+			}
 			m.mode = normalMode
+			m.err = nil
 		}
-		if msg.Type == tea.KeyTab {
-			c := m.cursor
-			id := getID(m.atCursor())
-			if m.moveSameParent(-1) {
-				above := getID(m.atCursor())
-				m.all.Move(id, above, "", task.Below)
-				m.updateVisible()
-				m.setCursor(c)
-			}
-		} else if msg.Type == tea.KeyShiftTab {
-			c := m.cursor
-			id := getID(m.atCursor())
-			if m.moveUpLeft() {
-				above := getID(m.atCursor())
-				newParent := m.all.Parent[above]
-				m.all.Move(id, newParent, above, 1)
-				m.updateVisible()
-				m.setCursor(c)
-			}
+		// tasks are indented while their titles are typed too
+		if m.mode.editing() && (msg.Type == tea.KeyTab || msg.Type == tea.KeyShiftTab) {
+			cmds = append(cmds, m.perform(keymap[msg.String()], ""))
 		}
 		switch m.mode {
 		case titleMode:
@@ -150,122 +303,243 @@ func (m app) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				id := getID(m.atCursor())
 				err := m.all.SetTitle(id, m.textinput.Value())
 				if err != nil {
-					panic(err)
+					cmds = append(cmds, report(err))
+				}
+				// sorted by title, the task may have moved
+				m.updateVisible()
+				if i := m.indexOf(id); i >= 0 {
+					m.setCursor(i)
 				}
 			} else {
 				m.textinput, cmd = m.textinput.Update(msg)
 				m.textinput.Width = len(m.textinput.Value()) + 1
 				cmds = append(cmds, cmd)
 			}
-		case dateMode:
+		case dateMode, startMode:
 			if msg.Type == tea.KeyEnter {
+				mode := m.mode
 				m.mode = normalMode
-				id := getID(m.atCursor())
-				err := m.all.SetDue(id, m.dateinput.Value())
-				if err != nil {
-					panic(err)
+				if err := m.setDate(mode, m.dateinput.Value()); err != nil {
+					cmds = append(cmds, report(err))
 				}
 			} else {
 				m.dateinput, cmd = m.dateinput.Update(msg)
 				cmds = append(cmds, cmd)
 			}
-		case normalMode:
-			anchor := task.Below
-			switch msg.String() {
-			case "alt+1":
-				m.tabs.Set(0)
-				m.setCursor(0)
-				m.updateVisible()
-			case "alt+2":
-				m.tabs.Set(1)
-				m.setCursor(0)
-				m.updateVisible()
-			case "alt+3":
-				m.tabs.Set(2)
-				m.setCursor(0)
-				m.updateVisible()
-			case tea.KeyEnter.String():
-				id := getID(m.atCursor())
-				t := m.all.Nodes[id]
-				// TODO: handle error without panicking
-				m.all.SetFolded(id, !t.Folded)
-				// if err != nil {
-				// 	panic(err)
-				// }
-				m.updateVisible()
-			case "i":
-				m.edit()
-			case tea.KeyDelete.String():
-				id := getID(m.atCursor())
-				if len(id) > 0 {
-					err := m.all.Remove(id)
-					if err != nil {
-						panic(err)
-					}
-					m.updateVisible()
-					m.setCursor(m.cursor)
-				}
-			case "d":
-				m.dateinput.SetValue(nil)
-				m.mode = dateMode
-			case "j", tea.KeyDown.String():
-				m.setCursor(m.cursor + 1)
-			case "k", tea.KeyUp.String():
-				m.setCursor(m.cursor - 1)
-			case "t":
-				id := getID(m.atCursor())
-				now := time.Now()
-				var err error
-				if m.all.Nodes[id].Done == nil {
-					err = m.all.SetDone(id, &now)
-				} else {
-					err = m.all.SetDone(id, nil)
-				}
-				if err != nil {
-					panic(err)
-				}
-				m.updateVisible()
-			case "K":
-				id := getID(m.atCursor())
-				if m.moveSameParent(-1) {
-					above := getID(m.atCursor())
-					m.all.Move(above, m.all.Parent[id], id, task.Below)
-					m.updateVisible()
-				}
-			case "J":
-				c := m.cursor
-				id := getID(m.atCursor())
-				if m.moveSameParent(1) {
-					above := getID(m.atCursor())
-					m.all.Move(id, m.all.Parent[id], above, task.Below)
-					m.updateVisible()
-					m.setCursor(c)
-					m.moveSameParent(1)
-				}
-			case "O":
-				anchor = task.Above
-				fallthrough
-			case "o":
-				id := getID(m.atCursor())
-				parent := m.all.Parent[id]
-				if len(parent) == 0 {
-					parent = "root"
-				}
-				err := m.all.Add(parent, id, anchor)
-				if err != nil {
-					panic(err)
+		case remindMode:
+			if msg.Type == tea.KeyEnter {
+				m.mode = normalMode
+				if err := m.setReminders(m.textinput.Value()); err != nil {
+					m.warning = err
 				}
-				m.updateVisible()
-				m.setCursor(m.cursor + int(anchor))
-				m.edit()
+			} else {
+				m.textinput, cmd = m.textinput.Update(msg)
+				m.textinput.Width = len(m.textinput.Value()) + 1
+				cmds = append(cmds, cmd)
+			}
+		case normalMode:
+			handled, err := m.viewKey(msg.String())
+			if err != nil {
+				cmds = append(cmds, report(err))
+			}
+			if handled {
+				break
 			}
+			if a, ok := keymap[keyName(msg)]; ok {
+				cmds = append(cmds, m.perform(a, ""))
+			}
+		case paletteMode:
+			cmds = append(cmds, m.paletteKey(msg))
+		case helpMode:
+			m.helpKey(msg.String())
 		}
 	}
+	if cmd := m.afterHooks(); cmd != nil {
+		cmds = append(cmds, cmd)
+	}
 	m.viewport.SetContent(m.renderTasks())
 
 	return m, tea.Batch(cmds...)
 }
 
+// hooksDoneMsg reports how the hooks for after the changes went
+type hooksDoneMsg struct{ err error }
+
+// afterHooks runs the after- hooks queued by updateVisible in the background,
+// as they may take a while
+func (m *app) afterHooks() tea.Cmd {
+	after := m.after
+	m.after = nil
+	if len(after) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		for _, run := range after {
+			if err := run(); err != nil {
+				return hooksDoneMsg{err}
+			}
+		}
+		return hooksDoneMsg{}
+	}
+}
+
+// fail shows err to the user. The in-memory state may be broken by now, so it
+// is saved next to the task file rather than over it.
+func (m *app) fail(err error) {
+	m.err = err
+	if path, saveErr := m.storage.Emergency(m.all); saveErr == nil {
+		m.err = fmt.Errorf("%v (copy saved to %s)", err, path)
+	}
+}
+
+// handle applies a request sent over the control socket
+func (m *app) handle(req controlRequest) (task.ID, error) {
+	if m.err != nil && req.Cmd != "jump" {
+		return "", fmt.Errorf("taskman has an unresolved error: %v", m.err)
+	}
+	switch req.Cmd {
+	case "add":
+		id, err := addTask(&m.all, req)
+		if err != nil {
+			return "", err
+		}
+		m.updateVisible()
+		return id, nil
+	case "done":
+		now := time.Now()
+		if err := m.all.SetDone(req.ID, &now); err != nil {
+			return "", err
+		}
+		m.updateVisible()
+		return req.ID, nil
+	case "jump":
+		return req.ID, m.jump(req.ID)
+	case "reload":
+		data, err := m.storage.Fetch()
+		if err != nil {
+			return "", err
+		}
+		// the tabs are the app's own, and may be ahead of the file
+		data.Views = m.all.Views
+		m.all = data
+		m.updateVisible()
+		m.setCursor(m.cursor)
+		return "", nil
+	case "replace":
+		if req.Tasks == nil || storage.Hash(m.all) != req.Base {
+			return "", api.ErrStale
+		}
+		tasks := *req.Tasks
+		tasks.Views, tasks.Hook = m.all.Views, m.all.Hook
+		m.all = tasks
+		m.updateVisible()
+		m.setCursor(m.cursor)
+		return "", nil
+	}
+	return "", fmt.Errorf("%w %q", errUnknownCmd, req.Cmd)
+}
+
+// jump moves the cursor to a task, unfolding its parents and switching to the
+// first tab if that is what it takes to show it
+func (m *app) jump(id task.ID) error {
+	if _, found := m.all.Nodes[id]; !found || id == "root" {
+		return task.ErrBadID
+	}
+	i := m.reveal(id)
+	if i < 0 && m.tabs.Value() != 0 {
+		m.switchTab(0)
+		i = m.reveal(id)
+	}
+	if i < 0 {
+		return errors.New("task is hidden by the filter")
+	}
+	m.setCursor(i)
+	return nil
+}
+
+// reveal unfolds the parents of a task, and unhoists unless it is under the
+// hoisted task, returning where it ends up
+func (m *app) reveal(id task.ID) int {
+	under := false
+	for parent := m.all.Parent[id]; parent != "root" && parent != ""; parent = m.all.Parent[parent] {
+		m.setFolded(parent, false)
+		under = under || parent == m.hoisted
+	}
+	if !under {
+		m.hoisted = ""
+	}
+	m.updateVisible()
+	return m.indexOf(id)
+}
+
+func (m app) indexOf(id task.ID) int {
+	for i, path := range m.visible {
+		if getID(path) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// yank copies the task at the cursor and everything under it to the clipboard
+// as a markdown checklist
+func (m *app) yank() error {
+	id := getID(m.atCursor())
+	if len(id) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	if err := (format.Markdown{}).Encode(&b, format.Subtree(m.all, id), "root"); err != nil {
+		return err
+	}
+	return clipboard.WriteAll(b.String())
+}
+
+// paste adds the tasks of a markdown checklist in the clipboard below the cursor
+func (m *app) paste() error {
+	s, err := clipboard.ReadAll()
+	if err != nil {
+		return err
+	}
+	pasted, err := format.Markdown{}.Decode(strings.NewReader(s))
+	if err != nil {
+		return err
+	}
+	anchor := getID(m.atCursor())
+	parent := m.all.Parent[anchor]
+	if len(parent) == 0 {
+		parent = m.root()
+	}
+	if _, _, err := m.all.Merge(pasted, parent, format.Markdown{}.Fields()); err != nil {
+		return err
+	}
+	for _, id := range pasted.Children["root"] {
+		if err := m.all.Move(id, parent, anchor, task.Below); err != nil {
+			return err
+		}
+		anchor = id
+	}
+	return nil
+}
+
+// setReminders sets the comma separated reminders of the task at the cursor
+func (m *app) setReminders(s string) error {
+	reminders := []string{}
+	for _, r := range strings.Split(s, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			reminders = append(reminders, r)
+		}
+	}
+	if err := remind.Validate(reminders); err != nil {
+		return err
+	}
+	if err := m.all.SetReminders(getID(m.atCursor()), reminders); err != nil {
+		return err
+	}
+	m.updateVisible()
+	return nil
+}
+
 func (m *app) edit() {
 	m.mode = titleMode
 	t := m.all.Nodes[getID(m.atCursor())]
@@ -278,10 +552,18 @@ func (m *app) setCursor(value int) {
 	size := len(m.visible)
 	m.cursor = clamp(value, 0, max(size-1, 0))
 
+	if m.view.grid() {
+		m.viewport.YOffset = 0
+		return
+	}
+
 	// for when no tasks
 	if size == 0 {
 		return
 	}
+	if m.view == treeView {
+		m.tab().Cursor = getID(m.visible[m.cursor])
+	}
 
 	tasks := m.visible[:m.cursor]
 	linesBeforeCursor := 0
@@ -339,30 +621,83 @@ func (m *app) moveUpLeft() bool {
 }
 
 func (m *app) updateVisible() {
-	m.visible = traverse(m.all, "root")[1:]
-
-	m.visible = m.filter(m.visible, m.predicates[m.tabs.Value()])
+	keep := m.keep()
+	switch m.view {
+	case agendaView:
+		m.visible = m.agenda()
+	case calendarView:
+		m.visible = m.byDay()[m.calendar.day]
+	case boardView:
+		workflow := m.all.Workflow()
+		m.board.column = clamp(m.board.column, 0, len(workflow)-1)
+		m.visible = m.cards(workflow[m.board.column])
+	case burndownView, reportView:
+		// charts have no tasks to act on
+		m.visible = nil
+	default:
+		if m.view == treeView {
+			m.visible = m.sorted(m.root())
+		} else {
+			m.visible = m.traverse(m.root())[1:]
+		}
+		m.visible = m.filter(m.visible, keep)
+	}
+	m.headings = nil
+	if m.view == treeView {
+		m.visible, m.headings = m.grouped(m.visible)
+	}
 	// TODO: clamp cursor
 	// m.setCursor(m.cursor) // for when we switch tabs and previous cursor is out of reach
 
-	// save
-	m.storage.Sync(m.all)
+	// save, unless the last failure has not been dismissed yet
+	m.saveTab()
+	if m.err == nil {
+		if _, err := m.storage.Sync(m.all); err != nil {
+			m.fail(err)
+		} else if m.storage.After != nil {
+			// only changes that made it to the file get their after- hooks
+			if after := m.storage.After(); after != nil {
+				m.after = append(m.after, after)
+			}
+		}
+	}
 
 	sum, done := 0, 0
 	for _, path := range m.visible {
 		t := m.all.Nodes[getID(path)]
 		// do not count those who are only there because its parent/child is
-		if m.predicates[m.tabs.Value()](t) {
+		if keep(t) {
 			if t.Done != nil {
 				done++
 			}
 			sum++
 		}
 	}
+	info := []string{}
+	if m.view != treeView {
+		info = append(info, viewNames[m.view])
+	}
+	switch {
+	case m.view == calendarView:
+		info = append(info, m.calendar.day.Format("January 2006"))
+	case m.view == boardView && m.board.project != "root":
+		info = append(info, "› "+m.all.Nodes[m.board.project].Title)
+	case m.view == burndownView && m.burndown != "root":
+		info = append(info, "› "+m.all.Nodes[m.burndown].Title)
+	case m.root() != "root":
+		info = append(info, "› "+m.all.Nodes[m.root()].Title)
+	}
+	if m.view == treeView {
+		info = append(info, m.arrangement.describe()...)
+	}
+	if m.syncStatus != "" {
+		info = append(info, m.syncStatus)
+	}
 	if sum > 0 {
 		percent := done * 100 / sum
-		m.tabs.Info = lipgloss.NewStyle().Foreground(ui.Secondary).Render(strconv.Itoa(percent) + "%")
+		info = append(info, strconv.Itoa(percent)+"%")
 	}
+	m.tabs.Info = lipgloss.NewStyle().Foreground(ui.Secondary).Render(strings.Join(info, "  "))
 }
 
 func (m *app) filter(paths []path, f predicate) []path {
@@ -384,6 +719,51 @@ func (m *app) filter(paths []path, f predicate) []path {
 	return arr
 }
 
+// layout sizes the viewport, leaving room for the dates above the timeline
+func (m *app) layout() {
+	m.viewport.Height = m.height - headerHeight - footerHeight
+	if m.showHints {
+		m.viewport.Height--
+	}
+	if m.view == timelineView {
+		m.viewport.Height--
+	}
+}
+
+// root is the hoisted task, or the root of all tasks
+func (m app) root() task.ID {
+	if _, found := m.all.Nodes[m.hoisted]; found {
+		return m.hoisted
+	}
+	return "root"
+}
+
+// hoist shows the subtasks of a task as if they were top level, unfolding it
+// so that there is something to show
+func (m *app) hoist(id task.ID) {
+	m.hoisted = id
+	if id == "root" {
+		m.hoisted = ""
+	}
+	m.setFolded(id, false)
+	switch m.view {
+	case boardView:
+		m.board.project = m.root()
+	case burndownView:
+		m.burndown = m.root()
+	}
+	m.updateVisible()
+	m.setCursor(0)
+}
+
+// unhoist shows the parent of the hoisted task, with the cursor on the task
+func (m *app) unhoist() {
+	if root := m.root(); root != "root" {
+		m.hoist(m.all.Parent[root])
+		m.setCursor(max(m.indexOf(root), 0))
+	}
+}
+
 func (m app) atCursor() path {
 	// if no items visible
 	if m.cursor >= len(m.visible) {
@@ -402,6 +782,19 @@ func getID(path path) task.ID {
 // this function is needed to figure out the height of a task for scrolling to work properly
 // this is because they can have varying heights due to custom spacing between groups
 func (m app) sizeOf(i int) int {
+	if m.view == agendaView {
+		return m.agendaSizeOf(i)
+	}
+	if m.headings != nil {
+		switch {
+		case m.headings[i] == "":
+			return 1
+		case i == 0:
+			return 2
+		default:
+			return 3
+		}
+	}
 	var (
 		currentPath = m.visible[i]
 		prevPath    path
@@ -420,26 +813,121 @@ func (m app) sizeOf(i int) int {
 	return 1
 }
 
+// progress is how many of the tasks under a task are done
+type progress struct {
+	done, total int
+}
+
+// tally counts the tasks under each task that pass the current filter, and
+// how many of them are done, folded or not
+func (m app) tally() map[task.ID]progress {
+	keep := m.keep()
+	tally := map[task.ID]progress{}
+	var walk func(id task.ID) progress
+	walk = func(id task.ID) progress {
+		sum := progress{}
+		for _, c := range m.all.Children[id] {
+			below := walk(c)
+			sum.done += below.done
+			sum.total += below.total
+			if t := m.all.Nodes[c]; keep(t) {
+				sum.total++
+				if t.Done != nil {
+					sum.done++
+				}
+			}
+		}
+		tally[id] = sum
+		return sum
+	}
+	walk(m.root())
+	return tally
+}
+
 // View renders the program's UI, which is just a string. The view is
 // rendered after every Update.
 func (m app) View() string {
 	statusline := ""
 	{
 		switch m.mode {
-		case dateMode:
+		case dateMode, startMode:
 			statusline = m.dateinput.View()
+		case titleMode:
+			if !m.view.inline() {
+				statusline = lipgloss.NewStyle().Foreground(ui.Secondary).Render("title: ") + m.textinput.View()
+			}
+		case remindMode:
+			statusline = lipgloss.NewStyle().Foreground(ui.Secondary).Render("remind: ") + m.textinput.View()
+		case paletteMode:
+			statusline = lipgloss.NewStyle().Foreground(ui.Secondary).Render(":") + m.textinput.View()
+		}
+		if m.notice != "" {
+			statusline = ui.RenderNotice(m.notice)
 		}
+		if m.warning != nil {
+			statusline = ui.RenderError(m.warning)
+		}
+		if m.err != nil {
+			statusline = ui.RenderError(m.err)
+		}
+	}
+	header := m.tabs.View()
+	if m.view == timelineView {
+		header += m.renderScale() + "\n"
 	}
-	return m.tabs.View() + m.viewport.View() + "\n" + statusline
+	body := m.viewport.View()
+	if m.mode == paletteMode {
+		body = m.renderPalette(body)
+	}
+	if m.showHints {
+		statusline += "\n" + m.renderHints()
+	}
+	return header + body + "\n" + statusline
 }
 
 func (m app) renderTasks() string {
+	if m.mode == helpMode {
+		return m.renderHelp()
+	}
+	switch m.view {
+	case agendaView:
+		return m.renderAgenda()
+	case calendarView:
+		return m.renderCalendar()
+	case boardView:
+		return m.renderBoard()
+	case timelineView:
+		return m.renderTimeline()
+	case burndownView:
+		id := m.burndown
+		if _, found := m.all.Nodes[id]; !found {
+			id = m.root()
+		}
+		title := "all tasks"
+		if id != "root" {
+			title = m.all.Nodes[id].Title
+		}
+		b := stats.ComputeBurndown(m.all, id, time.Now())
+		return renderBurndown(b, title, m.viewport.Width, m.viewport.Height, time.Now())
+	case reportView:
+		r := stats.Compute(m.all, m.root(), m.bucket, periods[m.bucket], time.Now())
+		return renderReport(r, m.viewport.Width)
+	}
+	tally := m.tally()
 	s := ""
 	for i, currentPath := range m.visible {
 		// s += strconv.Itoa(i) + "line\n"
 		task := m.all.Nodes[getID(currentPath)]
+		task.Folded = m.folded(getID(currentPath))
 
-		if m.sizeOf(i) == 2 {
+		if m.headings != nil {
+			if heading := m.headings[i]; heading != "" {
+				if i > 0 {
+					s += "\n"
+				}
+				s += ui.RenderSection(heading) + "\n"
+			}
+		} else if m.sizeOf(i) == 2 {
 			s += "\n"
 		}
 
@@ -466,6 +954,9 @@ func (m app) renderTasks() string {
 				title = title.Copy().Foreground(ui.Faded)
 			}
 			s += title.Render(task.Title)
+			if p := tally[getID(currentPath)]; p.total > 0 {
+				s += ui.RenderProgress(p.done, p.total, task.Folded)
+			}
 		}
 		if task.Done == nil {
 			s += ui.RenderDue(task)
@@ -475,13 +966,13 @@ func (m app) renderTasks() string {
 	return s
 }
 
-func traverse(m task.Tasks, id task.ID) []path {
+func (m app) traverse(id task.ID) []path {
 	all := []path{{id}}
-	if m.Nodes[id].Folded {
+	if m.folded(id) {
 		return all
 	}
-	for _, child := range m.Children[id] {
-		childPaths := traverse(m, child)
+	for _, child := range m.all.Children[id] {
+		childPaths := m.traverse(child)
 		for _, subp := range childPaths {
 			path := append([]task.ID{id}, subp...)
 			all = append(all, path)
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+// put adds a task with a known ID under parent, titled after the ID unless it
+// has a title of its own
+func put(tasks *task.Tasks, id, parent task.ID, t task.Task) {
+	if t.Title == "" {
+		t.Title = string(id)
+	}
+	tasks.Nodes[id] = t
+	tasks.Children[parent] = append(tasks.Children[parent], id)
+	tasks.Parent[id] = parent
+}
+
+func TestTally(t *testing.T) {
+	done := time.Now()
+	tasks := task.NewTasks()
+	put(&tasks, "release", "root", task.Task{Folded: true})
+	put(&tasks, "notes", "release", task.Task{Done: &done})
+	put(&tasks, "build", "release", task.Task{})
+	put(&tasks, "docs", "build", task.Task{Done: &done})
+	put(&tasks, "tests", "build", task.Task{Due: &done})
+
+	m := newApp(nil, tasks)
+	tests := []struct {
+		tab  int
+		id   task.ID
+		want progress
+	}{
+		{0, "release", progress{2, 4}},
+		{0, "build", progress{1, 2}},
+		{0, "docs", progress{}},
+		// only the tasks in the tab count
+		{1, "release", progress{2, 3}},
+		{1, "build", progress{1, 1}},
+	}
+	for _, tt := range tests {
+		m.tabs.Set(tt.tab)
+		if got := m.tally()[tt.id]; got != tt.want {
+			t.Errorf("tab %d, %s: got %v, want %v", tt.tab, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestEmptyCursor(t *testing.T) {
+	store := storage.NewJSON(filepath.Join(t.TempDir(), "tasks.json"))
+	tasks, _ := store.Fetch()
+	m := newApp(store, tasks)
+
+	// nothing is under the cursor, so there is nothing to act on
+	for _, a := range actions {
+		for _, args := range []string{"", "tomorrow"} {
+			switch a.name {
+			case "new", "new-above", "palette", "filter", "tab", "tab-new", "tab-rename", "view", "sort", "group", "jump", "export", "paste":
+				continue
+			}
+			if err := a.run(&m, args); err != nil {
+				t.Errorf("%s %q: %v", a.name, args, err)
+			}
+			m.mode = normalMode
+		}
+	}
+	if len(m.all.Nodes) != 1 {
+		t.Errorf("got %d tasks, want only the root", len(m.all.Nodes))
+	}
+}
+
+func TestEditSaves(t *testing.T) {
+	store := storage.NewJSON(filepath.Join(t.TempDir(), "tasks.json"))
+	tasks, _ := store.Fetch()
+	put(&tasks, "a", "root", task.Task{})
+	m := newApp(store, tasks)
+	m.updateVisible()
+	m.setCursor(m.indexOf("a"))
+
+	if err := keymap["i"].run(&m, "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := store.Fetch()
+	if err != nil || saved.Nodes["a"].Title != "renamed" {
+		t.Errorf("got %q, %v, want the new title saved", saved.Nodes["a"].Title, err)
+	}
+}
+
+func TestExportFailure(t *testing.T) {
+	store := storage.NewJSON(filepath.Join(t.TempDir(), "tasks.json"))
+	tasks, _ := store.Fetch()
+	m := newApp(store, tasks)
+
+	if err := m.export("md " + filepath.Join(t.TempDir(), "missing", "tasks.md")); err == nil {
+		t.Fatal("saved to a directory that does not exist")
+	}
+	if m.notice != "" {
+		t.Errorf("got notice %q after a failure", m.notice)
+	}
+}
+
+func TestAfterHooks(t *testing.T) {
+	store := storage.NewJSON(filepath.Join(t.TempDir(), "tasks.json"))
+	taken, ran := 0, 0
+	store.After = func() func() error {
+		taken++
+		return func() error {
+			ran++
+			return errors.New("chat is down")
+		}
+	}
+	tasks, _ := store.Fetch()
+	m := newApp(store, tasks)
+	m.after, taken = nil, 0
+
+	// nothing is saved while a failure has not been dismissed
+	m.err = errors.New("boom")
+	m.updateVisible()
+	if taken != 0 || m.afterHooks() != nil {
+		t.Errorf("hooks to run for changes that were not saved")
+	}
+	m.err = nil
+	m.updateVisible()
+	cmd := m.afterHooks()
+	if taken != 1 || cmd == nil {
+		t.Fatalf("no hooks to run for saved changes")
+	}
+	if ran != 0 {
+		t.Fatalf("hooks ran on the update loop")
+	}
+	model, _ := m.Update(cmd())
+	if ran != 1 || model.(app).warning == nil {
+		t.Errorf("ran %d times, warning %v", ran, model.(app).warning)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+// arrangement is how the tree view orders and groups the tasks, leaving the
+// stored order alone
+type arrangement struct {
+	order int
+	group int
+	// flat lists every task on its own rather than under its parent, sorted
+	// all together
+	flat bool
+}
+
+// ordering sorts tasks, keeping them in the order they were put in when less
+// is nil
+type ordering struct {
+	name string
+	less func(a, b task.Task) bool
+}
+
+var orderings = []ordering{
+	{"manual", nil},
+	{"due", func(a, b task.Task) bool {
+		return a.Due != nil && (b.Due == nil || a.Due.Before(*b.Due))
+	}},
+	{"created", func(a, b task.Task) bool {
+		return a.Created.Before(b.Created)
+	}},
+	{"priority", func(a, b task.Task) bool {
+		return a.Priority != "" && (b.Priority == "" || a.Priority < b.Priority)
+	}},
+	{"title", func(a, b task.Task) bool {
+		return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+	}},
+	// the tasks left to do come first, then those done the longest ago
+	{"done", func(a, b task.Task) bool {
+		if a.Done == nil || b.Done == nil {
+			return a.Done == nil && b.Done != nil
+		}
+		return a.Done.Before(*b.Done)
+	}},
+}
+
+// grouping puts the tasks under headings. of returns the heading of a task,
+// and a rank that orders the groups: the lowest of those in the group.
+type grouping struct {
+	name string
+	of   func(m app, id task.ID) (heading string, rank string)
+}
+
+var groupings = []grouping{
+	{"none", nil},
+	{"due", func(m app, id task.ID) (string, string) {
+		due := m.all.Nodes[id].Due
+		if due == nil {
+			return "No date", "~"
+		}
+		return section(*due, time.Now()), due.Format("2006-01-02")
+	}},
+	{"tag", func(m app, id task.ID) (string, string) {
+		tags := m.all.Nodes[id].Tags
+		if len(tags) == 0 {
+			return "Untagged", "~"
+		}
+		return "#" + tags[0], tags[0]
+	}},
+	{"project", func(m app, id task.ID) (string, string) {
+		for m.all.Parent[id] != m.root() && m.all.Parent[id] != "" {
+			id = m.all.Parent[id]
+		}
+		for i, c := range m.all.Children[m.root()] {
+			if c == id {
+				return m.all.Nodes[id].Title, fmt.Sprintf("%08d", i)
+			}
+		}
+		return m.all.Nodes[id].Title, "~"
+	}},
+}
+
+// errArranged stops tasks from being moved around while they are not shown in
+// their stored order
+var errArranged = badArgs("tasks are only moved around in the manual order, ungrouped")
+
+// arranged reports whether the tasks are shown in another order than the one
+// they are stored in
+func (m app) arranged() bool {
+	a := m.arrangement
+	return m.view == treeView && (a.order != 0 || a.group != 0 || a.flat)
+}
+
+// describe says how the tasks are arranged, for the tab bar
+func (a arrangement) describe() []string {
+	info := []string{}
+	if a.flat {
+		info = append(info, "flat")
+	}
+	if a.order != 0 {
+		info = append(info, "by "+orderings[a.order].name)
+	}
+	if a.group != 0 {
+		info = append(info, "grouped by "+groupings[a.group].name)
+	}
+	return info
+}
+
+// sorted lists the tasks under root in the order of the arrangement, either as
+// a tree with every parent's subtasks sorted, or flat as if they were all top
+// level tasks
+func (m app) sorted(root task.ID) []path {
+	less := orderings[m.arrangement.order].less
+	children := func(id task.ID) []task.ID {
+		ids := append([]task.ID{}, m.all.Children[id]...)
+		if less != nil {
+			sort.SliceStable(ids, func(i, j int) bool {
+				return less(m.all.Nodes[ids[i]], m.all.Nodes[ids[j]])
+			})
+		}
+		return ids
+	}
+	paths := []path{}
+	if m.arrangement.flat {
+		var walk func(id task.ID)
+		walk = func(id task.ID) {
+			for _, c := range m.all.Children[id] {
+				paths = append(paths, path{root, c})
+				walk(c)
+			}
+		}
+		walk(root)
+		if less != nil {
+			sort.SliceStable(paths, func(i, j int) bool {
+				return less(m.all.Nodes[getID(paths[i])], m.all.Nodes[getID(paths[j])])
+			})
+		}
+		return paths
+	}
+	var walk func(p path)
+	walk = func(p path) {
+		if m.folded(getID(p)) {
+			return
+		}
+		for _, c := range children(getID(p)) {
+			cp := append(append(path{}, p...), c)
+			paths = append(paths, cp)
+			walk(cp)
+		}
+	}
+	walk(path{root})
+	return paths
+}
+
+// grouped puts the top level tasks, and whatever is shown under them, in
+// groups. It returns the heading of each path, empty unless it starts a group.
+func (m app) grouped(paths []path) ([]path, []string) {
+	of := groupings[m.arrangement.group].of
+	if of == nil {
+		return paths, nil
+	}
+	type group struct {
+		heading, rank string
+		paths         []path
+	}
+	groups := []*group{}
+	byHeading := map[string]*group{}
+	for i := 0; i < len(paths); {
+		j := i + 1
+		for j < len(paths) && len(paths[j]) > 2 {
+			j++
+		}
+		heading, rank := of(m, getID(paths[i]))
+		g := byHeading[heading]
+		if g == nil {
+			g = &group{heading: heading, rank: rank}
+			byHeading[heading] = g
+			groups = append(groups, g)
+		}
+		if rank < g.rank {
+			g.rank = rank
+		}
+		g.paths = append(g.paths, paths[i:j]...)
+		i = j
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].rank < groups[j].rank })
+	paths, headings := []path{}, []string{}
+	for _, g := range groups {
+		for i, p := range g.paths {
+			paths = append(paths, p)
+			if i == 0 {
+				headings = append(headings, g.heading)
+			} else {
+				headings = append(headings, "")
+			}
+		}
+	}
+	return paths, headings
+}
+
+// cycle picks the option named by args, or the one after current without
+func cycle(names []string, current int, args string) (int, error) {
+	if args == "" {
+		return (current + 1) % len(names), nil
+	}
+	for i, name := range names {
+		if strings.HasPrefix(name, args) {
+			return i, nil
+		}
+	}
+	return current, badArgs("pick one of " + strings.Join(names, ", "))
+}
+
+// rearrange shows the tasks in another arrangement, keeping the cursor on the
+// same task
+func (m *app) rearrange(a arrangement) {
+	id := getID(m.atCursor())
+	m.arrangement = a
+	m.updateVisible()
+	m.setCursor(max(m.indexOf(id), 0))
+}
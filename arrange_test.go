@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+func TestArrange(t *testing.T) {
+	day := func(d int) *time.Time {
+		t := time.Now().Truncate(time.Hour*24).AddDate(0, 0, d)
+		return &t
+	}
+	tasks := task.NewTasks()
+	put(&tasks, "release", "root", task.Task{Due: day(30)})
+	put(&tasks, "notes", "release", task.Task{Due: day(9), Tags: []string{"docs"}})
+	put(&tasks, "build", "release", task.Task{Due: day(-1)})
+	put(&tasks, "chores", "root", task.Task{})
+	put(&tasks, "dishes", "chores", task.Task{Due: day(0), Priority: "A"})
+
+	tests := []struct {
+		a    arrangement
+		want string
+	}{
+		{arrangement{}, "release notes build chores dishes"},
+		{arrangement{order: 1}, "release build notes chores dishes"},
+		{arrangement{order: 4}, "chores dishes release build notes"},
+		{arrangement{order: 1, flat: true}, "build dishes notes release chores"},
+		{arrangement{order: 3, flat: true}, "dishes release notes build chores"},
+		{arrangement{order: 1, flat: true, group: 3}, "[release] build notes release [chores] dishes chores"},
+		{arrangement{flat: true, group: 2}, "[#docs] notes [Untagged] release build chores dishes"},
+		{arrangement{order: 1, group: 1}, "[Later] release build notes [No date] chores dishes"},
+	}
+	for _, tt := range tests {
+		m := app{all: tasks, arrangement: tt.a, view: treeView}
+		paths, headings := m.grouped(m.sorted("root"))
+		got := []string{}
+		for i, p := range paths {
+			if headings != nil && headings[i] != "" {
+				got = append(got, "["+headings[i]+"]")
+			}
+			got = append(got, string(getID(p)))
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.a.describe(), strings.Join(got, " "), tt.want)
+		}
+	}
+}
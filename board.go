@@ -0,0 +1,182 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/td0m/taskman/task"
+	"github.com/td0m/taskman/ui"
+)
+
+// board is the state of the board view, which lays out the children of a
+// project in columns by status. The visible tasks are the cards of the
+// selected column.
+type board struct {
+	project task.ID
+	column  int
+}
+
+var (
+	columnStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(ui.Faded)
+	columnSelected = columnStyle.Copy().BorderForeground(ui.Secondary)
+)
+
+// project is the task a board is opened on: the hoisted one, or else the top
+// level task at the cursor, as long as it has subtasks
+func (m app) project() task.ID {
+	if root := m.root(); root != "root" {
+		return root
+	}
+	if p := m.atCursor(); len(p) > 1 && len(m.all.Children[p[1]]) > 0 {
+		return p[1]
+	}
+	return "root"
+}
+
+// cards lists the subtasks of the project in the given status
+func (m *app) cards(status string) []path {
+	project := m.board.project
+	if _, found := m.all.Nodes[project]; !found {
+		project = m.root()
+	}
+	keep := m.keep()
+	paths := []path{}
+	for _, id := range m.all.Children[project] {
+		if t := m.all.Nodes[id]; m.all.Status(t) == status && keep(t) {
+			paths = append(paths, path{project, id})
+		}
+	}
+	return paths
+}
+
+// selectColumn moves to another column, staying on the same row if it can
+func (m *app) selectColumn(i int) {
+	m.board.column = clamp(i, 0, len(m.all.Workflow())-1)
+	m.updateVisible()
+	m.setCursor(m.cursor)
+}
+
+// boardKeys are the keys that differ in the board view
+var boardKeys = []viewBinding{
+	{binding{[]string{"h", "l"}, "column", "select the previous or next column"}, func(m *app, key string) error {
+		by := 1
+		if key == "h" {
+			by = -1
+		}
+		m.selectColumn(m.board.column + by)
+		return nil
+	}},
+	{binding{[]string{"H", "L"}, "status", "move the card to the previous or next status"}, func(m *app, key string) error {
+		workflow := m.all.Workflow()
+		id := getID(m.atCursor())
+		to := m.board.column - 1
+		if key == "L" {
+			to = m.board.column + 1
+		}
+		if len(id) == 0 || to < 0 || to >= len(workflow) {
+			return nil
+		}
+		if err := m.all.SetStatus(id, workflow[to]); err != nil {
+			return err
+		}
+		m.selectColumn(to)
+		m.setCursor(max(m.indexOf(id), 0))
+		return nil
+	}},
+	{binding{[]string{"K", "J"}, "reorder", "move the card up or down"}, func(m *app, key string) error {
+		id := getID(m.atCursor())
+		pos, by := task.Above, -1
+		if key == "J" {
+			pos, by = task.Below, 1
+		}
+		i := m.cursor + by
+		if len(id) == 0 || i < 0 || i >= len(m.visible) {
+			return nil
+		}
+		// the cards of other columns in between stay where they are
+		if err := m.all.Move(id, m.all.Parent[id], getID(m.visible[i]), pos); err != nil {
+			return err
+		}
+		m.updateVisible()
+		m.setCursor(i)
+		return nil
+	}},
+	{binding{[]string{"o", "O"}, "new", "add a card to the column"}, func(m *app, key string) error {
+		pos := task.Below
+		if key == "O" {
+			pos = task.Above
+		}
+		parent := m.board.project
+		if _, found := m.all.Nodes[parent]; !found {
+			parent = m.root()
+		}
+		added, err := m.all.Add(parent, getID(m.atCursor()), pos)
+		if err != nil {
+			return err
+		}
+		if err := m.all.SetStatus(added, m.all.Workflow()[m.board.column]); err != nil {
+			return err
+		}
+		m.updateVisible()
+		m.setCursor(max(m.indexOf(added), 0))
+		m.edit()
+		return nil
+	}},
+}
+
+// scroll returns which of n lines fit in the given height, keeping the cursor
+// in sight. If some do not fit, the last line is left to say so.
+func scroll(n, height, cursor int) (int, int) {
+	if n <= height {
+		return 0, n
+	}
+	shown := max(height-1, 0)
+	start := 0
+	if shown > 0 && cursor >= shown {
+		start = min(cursor-shown+1, n-shown)
+	}
+	return start, start + shown
+}
+
+func (m app) renderBoard() string {
+	workflow := m.all.Workflow()
+	w := m.viewport.Width/len(workflow) - 2
+	// the borders and the name of the status
+	height := m.viewport.Height - 3
+	if w < 4 || height < 1 {
+		return ""
+	}
+
+	columns := []string{}
+	for i, status := range workflow {
+		cards := m.cards(status)
+		selected := i == m.board.column
+		s := ui.RenderSection(runewidth.Truncate(status, w-3, "…")) +
+			overflow.Render(" "+strconv.Itoa(len(cards)))
+
+		cursor := -1
+		if selected {
+			cursor = m.cursor
+		}
+		start, end := scroll(len(cards), height, cursor)
+		for j := start; j < end; j++ {
+			t := m.all.Nodes[getID(cards[j])]
+			title := ui.Title(t)
+			if j == cursor && m.mode == normalMode {
+				title = title.Copy().Background(ui.Faded).Foreground(ui.Background)
+			}
+			s += "\n" + ui.RenderIcon(t) + title.Render(runewidth.Truncate(t.Title, w-3, "…"))
+		}
+		if end-start < len(cards) {
+			s += "\n" + overflow.Render("+"+strconv.Itoa(len(cards)-end+start)+" more")
+		}
+
+		style := columnStyle
+		if selected {
+			style = columnSelected
+		}
+		columns = append(columns, style.Copy().Width(w).Height(height+1).Render(s))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, columns...)
+}
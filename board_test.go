@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestScroll(t *testing.T) {
+	tests := []struct {
+		n, height, cursor int
+		start, end        int
+	}{
+		{3, 5, 0, 0, 3},
+		{5, 5, 4, 0, 5},
+		// the last line says how many more there are
+		{10, 5, -1, 0, 4},
+		{10, 5, 3, 0, 4},
+		{10, 5, 4, 1, 5},
+		{10, 5, 9, 6, 10},
+		{10, 1, 5, 0, 0},
+	}
+	for _, tt := range tests {
+		start, end := scroll(tt.n, tt.height, tt.cursor)
+		if start != tt.start || end != tt.end {
+			t.Errorf("scroll(%d, %d, %d): got %d-%d, want %d-%d", tt.n, tt.height, tt.cursor, start, end, tt.start, tt.end)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/td0m/taskman/stats"
+	"github.com/td0m/taskman/task"
+	"github.com/td0m/taskman/ui"
+)
+
+var idealLine = lipgloss.NewStyle().Foreground(ui.Faded)
+
+// subtree is the task the burndown view is opened on: the one at the cursor if
+// it has subtasks, or else the closest of its parents that does
+func (m app) subtree() task.ID {
+	p := m.atCursor()
+	for i := len(p) - 1; i > 0; i-- {
+		if len(m.all.Children[p[i]]) > 0 {
+			return p[i]
+		}
+	}
+	return m.root()
+}
+
+// burndownKeys are the keys of the burndown view, which has no tasks to act on
+var burndownKeys = []viewBinding{
+	{binding{[]string{"<"}, "up", "show the parent, then unhoist"}, func(m *app, _ string) error {
+		if m.burndown == m.root() {
+			m.unhoist()
+			return nil
+		}
+		m.burndown = m.all.Parent[m.burndown]
+		m.updateVisible()
+		return nil
+	}},
+}
+
+// renderBurndown plots how many tasks under a task were left open over time,
+// against a steady line down to none on its due date
+func renderBurndown(b stats.Burndown, title string, width, height int, now time.Time) string {
+	if b.Total == 0 {
+		return idealLine.Render("nothing under " + title + " to burn down")
+	}
+	left := b.Remaining(now)
+	summary := []string{ui.RenderSection(title), fmt.Sprintf("%d of %d left", left, b.Total)}
+	if b.Due != nil {
+		summary = append(summary, "due "+b.Due.Format("Mon Jan 2"))
+		ideal, _ := b.Ideal(now)
+		switch behind := int(math.Ceil(float64(left) - ideal)); {
+		case left == 0:
+			summary = append(summary, "all done")
+		case !now.Before(b.Due.AddDate(0, 0, 1)):
+			summary = append(summary, barLate.Render("overdue"))
+		case behind > 0:
+			summary = append(summary, barLate.Render(strconv.Itoa(behind)+" behind"))
+		default:
+			summary = append(summary, "on track")
+		}
+	}
+	s := strings.Join(summary, "  ") + "\n\n"
+
+	axis := len(strconv.Itoa(b.Total)) + 1
+	if width-axis < 4 || height-4 < 2 {
+		return s
+	}
+	canvas := ui.NewCanvas(width-axis, height-4)
+	w, h := canvas.Size()
+	span := b.To.Sub(b.From)
+	if span <= 0 {
+		span = time.Hour * 24
+	}
+	xOf := func(t time.Time) int {
+		return int(math.Round(float64(t.Sub(b.From)) / float64(span) * float64(w-1)))
+	}
+	timeAt := func(x int) time.Time {
+		return b.From.Add(time.Duration(float64(span) * float64(x) / float64(w-1)))
+	}
+	yOf := func(v float64) int {
+		return h - 1 - int(math.Round(v*float64(h-1)/float64(b.Total)))
+	}
+
+	if b.Due != nil {
+		canvas.Line(0, yOf(float64(b.Total)), xOf(b.Due.AddDate(0, 0, 1)), yOf(0), idealLine)
+	}
+	for x, y := 0, 0; x <= min(xOf(now), w-1); x++ {
+		t := timeAt(x)
+		remaining := b.Remaining(t)
+		style := bar
+		if ideal, ok := b.Ideal(t); ok && float64(remaining) > ideal+0.5 {
+			style = barLate
+		}
+		prev := y
+		y = yOf(float64(remaining))
+		if x == 0 {
+			prev = y
+		}
+		canvas.Line(max(x-1, 0), prev, x, y, style)
+	}
+
+	for i, row := range canvas.Rows() {
+		label := ""
+		switch i {
+		case 0:
+			label = strconv.Itoa(b.Total)
+		case h/4 - 1:
+			label = "0"
+		}
+		s += idealLine.Render(fmt.Sprintf("%*s ", axis-1, label)) + row + "\n"
+	}
+
+	// dates below the chart, leaving out those that would overlap
+	dates := []rune(strings.Repeat(" ", w/2))
+	put := func(x int, label string) {
+		x = clamp(x, 0, len(dates)-len(label))
+		if x >= 0 && strings.TrimSpace(string(dates[max(x-1, 0):min(x+len(label)+1, len(dates))])) == "" {
+			copy(dates[x:], []rune(label))
+		}
+	}
+	put(0, b.From.Format("Jan 2"))
+	if b.Due != nil {
+		put(xOf(b.Due.AddDate(0, 0, 1))/2, b.Due.Format("Jan 2"))
+	}
+	put(len(dates), b.To.Format("Jan 2"))
+	return s + strings.Repeat(" ", axis) + idealLine.Render(string(dates))
+}
@@ -0,0 +1,251 @@
+// Package caldav syncs tasks with a CalDAV calendar collection (RFC 4791),
+// storing each task as a VTODO resource named after its ID.
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/td0m/taskman/format"
+	"github.com/td0m/taskman/task"
+)
+
+// ErrPrecondition is returned when a resource changed on the server since its
+// ETag was last seen
+var ErrPrecondition = errors.New("resource was changed on the server")
+
+// Client talks to a single calendar collection
+type Client struct {
+	// URL of the collection, such as http://localhost:5232/user/tasks/
+	URL      string
+	Username string
+	Password string
+	HTTP     *http.Client
+}
+
+// Resource is a calendar object in the collection
+type Resource struct {
+	Href string `json:"href"`
+	ETag string `json:"etag"`
+}
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Prop struct {
+				ETag         string `xml:"DAV: getetag"`
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+			Status string `xml:"DAV: status"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:getetag/><d:resourcetype/></d:prop></d:propfind>`
+
+// List returns every calendar object in the collection, keyed by href
+func (c *Client) List(ctx context.Context) (map[string]Resource, error) {
+	ms, err := c.multistatus(ctx, "PROPFIND", c.URL, "1", propfindBody)
+	if err != nil {
+		return nil, err
+	}
+	resources := map[string]Resource{}
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstat {
+			if !ok(ps.Status) || ps.Prop.ResourceType.Collection != nil {
+				continue
+			}
+			href := c.path(r.Href)
+			resources[href] = Resource{Href: href, ETag: ps.Prop.ETag}
+		}
+	}
+	return resources, nil
+}
+
+// Fetch downloads the todos stored at hrefs with a single calendar-multiget
+// REPORT, returning them with the ETag of the resource they came from
+func (c *Client) Fetch(ctx context.Context, hrefs []string) (map[string]Fetched, error) {
+	fetched := map[string]Fetched{}
+	if len(hrefs) == 0 {
+		return fetched, nil
+	}
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop><d:getetag/><c:calendar-data/></d:prop>`)
+	for _, href := range hrefs {
+		body.WriteString("<d:href>")
+		xml.EscapeText(&body, []byte(href))
+		body.WriteString("</d:href>")
+	}
+	body.WriteString("</c:calendar-multiget>")
+
+	ms, err := c.multistatus(ctx, "REPORT", c.URL, "1", body.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstat {
+			if !ok(ps.Status) {
+				continue
+			}
+			todos, err := format.ReadCalendar(strings.NewReader(ps.Prop.CalendarData))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", r.Href, err)
+			}
+			href := c.path(r.Href)
+			fetched[href] = Fetched{Resource{href, ps.Prop.ETag}, todos}
+		}
+	}
+	return fetched, nil
+}
+
+// Fetched is a downloaded calendar object
+type Fetched struct {
+	Resource
+	Todos []format.Todo
+}
+
+// Put uploads todo. An empty etag creates a new resource and fails with
+// ErrPrecondition if one already exists, otherwise the resource must still
+// have that ETag. It returns the new ETag.
+func (c *Client) Put(ctx context.Context, href, etag string, todo format.Todo) (string, error) {
+	var body bytes.Buffer
+	if err := format.WriteCalendar(&body, []format.Todo{todo}); err != nil {
+		return "", err
+	}
+	req, err := c.request(ctx, "PUT", href, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	if etag == "" {
+		req.Header.Set("If-None-Match", "*")
+	} else {
+		req.Header.Set("If-Match", etag)
+	}
+	res, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+	if newTag := res.Header.Get("ETag"); newTag != "" {
+		return newTag, nil
+	}
+	// the server may not return the ETag if it changed the data while storing it
+	ms, err := c.multistatus(ctx, "PROPFIND", href, "0", propfindBody)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstat {
+			if ok(ps.Status) {
+				return ps.Prop.ETag, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// Delete removes the resource at href if it still has etag
+func (c *Client) Delete(ctx context.Context, href, etag string) error {
+	req, err := c.request(ctx, "DELETE", href, nil)
+	if err != nil {
+		return err
+	}
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Href returns where the task with the given ID is stored when it is created
+func (c *Client) Href(id task.ID) string {
+	return c.path(c.URL) + url.PathEscape(string(id)) + ".ics"
+}
+
+func (c *Client) multistatus(ctx context.Context, method, href, depth, body string) (multistatus, error) {
+	var ms multistatus
+	req, err := c.request(ctx, method, href, strings.NewReader(body))
+	if err != nil {
+		return ms, err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", depth)
+	res, err := c.do(req)
+	if err != nil {
+		return ms, err
+	}
+	defer res.Body.Close()
+	if err := xml.NewDecoder(res.Body).Decode(&ms); err != nil {
+		return ms, fmt.Errorf("%s %s: %w", method, href, err)
+	}
+	return ms, nil
+}
+
+func (c *Client) request(ctx context.Context, method, href string, body io.Reader) (*http.Request, error) {
+	base, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	u, err := base.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	return req, nil
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusPreconditionFailed {
+		res.Body.Close()
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, ErrPrecondition)
+	}
+	if res.StatusCode >= 300 {
+		res.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s", req.Method, req.URL.Path, res.Status)
+	}
+	return res, nil
+}
+
+// path returns the path of href, which servers may return as a full URL
+func (c *Client) path(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return u.EscapedPath()
+}
+
+func ok(status string) bool {
+	return status == "" || strings.Contains(status, " 200 ")
+}
@@ -0,0 +1,333 @@
+package caldav
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/td0m/taskman/format"
+	"github.com/td0m/taskman/task"
+)
+
+// State is what was known about each task at the end of the last sync. It is
+// what tells a task deleted on one side apart from a task created on the other.
+type State struct {
+	Tasks map[task.ID]Synced `json:"tasks"`
+}
+
+// Synced is a task as it was at the end of the last sync
+type Synced struct {
+	Resource
+	Hash string `json:"hash"`
+}
+
+// LoadState reads the state saved at path, which may not exist yet
+func LoadState(path string) (State, error) {
+	state := State{Tasks: map[task.ID]Synced{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	return state, json.Unmarshal(data, &state)
+}
+
+// Save writes the state to path
+func (s State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Policy decides which side wins when a task changed on both
+type Policy int
+
+const (
+	KeepLocal Policy = iota
+	KeepRemote
+)
+
+// Conflict is a task that changed on both sides since the last sync
+type Conflict struct {
+	ID     task.ID
+	Reason string
+}
+
+func (c Conflict) String() string {
+	return string(c.ID) + ": " + c.Reason
+}
+
+// Result holds the changes a sync pulled from the server, to be applied to the
+// local tasks with Apply
+type Result struct {
+	Pulled    []format.Todo
+	Deleted   []task.ID
+	Pushed    int
+	Conflicts []Conflict
+	State     State
+
+	// base is the hash of every local task at the start of the sync
+	base map[task.ID]string
+}
+
+func (r Result) String() string {
+	s := fmt.Sprintf("↑%d ↓%d", r.Pushed, len(r.Pulled)+len(r.Deleted))
+	if len(r.Conflicts) == 1 {
+		s += " 1 conflict"
+	} else if len(r.Conflicts) > 1 {
+		s += fmt.Sprintf(" %d conflicts", len(r.Conflicts))
+	}
+	return s
+}
+
+// Sync pushes local changes to the server and collects the changes made there
+// since state was saved. tasks is only read, so it can be a snapshot taken
+// before the sync started. If it fails partway, the state of the result is
+// still worth saving: the last one, along with what was pushed and deleted.
+func Sync(ctx context.Context, c *Client, tasks task.Tasks, state State, policy Policy) (res Result, err error) {
+	res = Result{State: State{Tasks: map[task.ID]Synced{}}, base: map[task.ID]string{}}
+	// what the server has been told so far
+	told := State{Tasks: map[task.ID]Synced{}}
+	for id, s := range state.Tasks {
+		told.Tasks[id] = s
+	}
+	defer func() {
+		if err != nil {
+			res.State = told
+		}
+	}()
+	remote, err := c.List(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	known := map[string]bool{}
+	pull := []string{}
+	conflict := func(id task.ID, reason string) {
+		res.Conflicts = append(res.Conflicts, Conflict{id, reason})
+	}
+	push := func(id task.ID, href, etag string) error {
+		newTag, err := c.Put(ctx, href, etag, format.TodoOf(tasks, id))
+		if err != nil {
+			return err
+		}
+		res.Pushed++
+		res.State.Tasks[id] = Synced{Resource{href, newTag}, res.base[id]}
+		told.Tasks[id] = res.State.Tasks[id]
+		return nil
+	}
+
+	for _, id := range sortedIDs(tasks.Nodes) {
+		if id == "root" {
+			continue
+		}
+		hash := hashOf(tasks.Nodes[id], tasks.Parent[id])
+		res.base[id] = hash
+		s, synced := state.Tasks[id]
+		if !synced {
+			// created here
+			href := c.Href(id)
+			known[href] = true
+			err := push(id, href, "")
+			if errors.Is(err, ErrPrecondition) {
+				conflict(id, "created on both sides")
+				if policy == KeepLocal {
+					err = push(id, href, remote[href].ETag)
+				} else {
+					pull, err = append(pull, href), nil
+				}
+			}
+			if err != nil {
+				return res, err
+			}
+			continue
+		}
+
+		known[s.Href] = true
+		r, exists := remote[s.Href]
+		changed := hash != s.Hash
+		switch {
+		case !exists && changed:
+			conflict(id, "changed here, deleted on the server")
+			if policy == KeepLocal {
+				err = push(id, s.Href, "")
+			} else {
+				res.Deleted = append(res.Deleted, id)
+			}
+		case !exists:
+			res.Deleted = append(res.Deleted, id)
+		case r.ETag != s.ETag && changed:
+			conflict(id, "changed on both sides")
+			if policy == KeepLocal {
+				err = push(id, s.Href, r.ETag)
+			} else {
+				pull = append(pull, s.Href)
+			}
+		case r.ETag != s.ETag:
+			pull = append(pull, s.Href)
+		case changed:
+			err = push(id, s.Href, s.ETag)
+			if errors.Is(err, ErrPrecondition) {
+				// changed on the server after it was listed, catch up next time
+				conflict(id, "changed on the server during the sync")
+				res.State.Tasks[id], err = s, nil
+			}
+		default:
+			res.State.Tasks[id] = s
+		}
+		if err != nil {
+			return res, err
+		}
+	}
+
+	// deleted here
+	for _, id := range sortedIDs(state.Tasks) {
+		s := state.Tasks[id]
+		if _, found := tasks.Nodes[id]; found {
+			continue
+		}
+		known[s.Href] = true
+		r, exists := remote[s.Href]
+		switch {
+		case !exists:
+		case r.ETag == s.ETag || policy == KeepLocal:
+			if r.ETag != s.ETag {
+				conflict(id, "deleted here, changed on the server")
+			}
+			if err := c.Delete(ctx, s.Href, r.ETag); err != nil && !errors.Is(err, ErrPrecondition) {
+				return res, err
+			}
+			delete(told.Tasks, id)
+		default:
+			conflict(id, "deleted here, changed on the server")
+			pull = append(pull, s.Href)
+		}
+	}
+
+	// created on the server
+	for href := range remote {
+		if !known[href] {
+			pull = append(pull, href)
+		}
+	}
+	sort.Strings(pull)
+
+	fetched, err := c.Fetch(ctx, pull)
+	if err != nil {
+		return res, err
+	}
+	for _, href := range pull {
+		f, ok := fetched[href]
+		if !ok {
+			continue
+		}
+		for _, todo := range f.Todos {
+			res.Pulled = append(res.Pulled, todo)
+			res.State.Tasks[todo.ID] = Synced{f.Resource, hashOf(todo.Task, parentOf(todo))}
+		}
+	}
+	return res, nil
+}
+
+// Apply makes the changes pulled from the server to tasks, and returns the
+// state to save once tasks have been saved. Tasks that were changed locally
+// while the sync was running are left alone, and will be synced again next time.
+func (r Result) Apply(tasks *task.Tasks) State {
+	state := State{Tasks: map[task.ID]Synced{}}
+	for id, s := range r.State.Tasks {
+		state.Tasks[id] = s
+	}
+	changedMeanwhile := func(id task.ID) bool {
+		t, found := tasks.Nodes[id]
+		return found && hashOf(t, tasks.Parent[id]) != r.base[id]
+	}
+
+	for _, id := range r.Deleted {
+		if _, found := tasks.Nodes[id]; !found {
+			continue
+		}
+		if changedMeanwhile(id) {
+			delete(state.Tasks, id)
+			continue
+		}
+		// children that were not deleted on the server move up instead
+		parent := tasks.Parent[id]
+		for _, c := range append([]task.ID{}, tasks.Children[id]...) {
+			tasks.Move(c, parent, id, task.Above)
+		}
+		tasks.Remove(id)
+	}
+
+	pulled := []format.Todo{}
+	for _, todo := range r.Pulled {
+		if changedMeanwhile(todo.ID) {
+			delete(state.Tasks, todo.ID)
+			continue
+		}
+		// what the server does not know of is kept
+		tasks.Nodes[todo.ID] = format.ICS{}.Fields().Overlay(tasks.Nodes[todo.ID], todo.Task)
+		pulled = append(pulled, todo)
+	}
+	// every pulled task is in Nodes by now, so they can be placed in any order
+	for _, todo := range pulled {
+		parent := parentOf(todo)
+		if _, found := tasks.Nodes[parent]; !found {
+			parent = "root"
+		}
+		if current, ok := tasks.Parent[todo.ID]; ok && current == parent {
+			continue
+		}
+		anchor, pos := task.ID(""), task.Below
+		if siblings := tasks.Children[parent]; todo.Order >= 0 && todo.Order < len(siblings) {
+			anchor, pos = siblings[todo.Order], task.Above
+		}
+		if err := tasks.Move(todo.ID, parent, anchor, pos); err != nil {
+			tasks.Move(todo.ID, "root", "", task.Below)
+		}
+	}
+	return state
+}
+
+func parentOf(todo format.Todo) task.ID {
+	if todo.Parent == "" {
+		return "root"
+	}
+	return todo.Parent
+}
+
+// hashOf identifies the content of a task as it is synced. The position among
+// siblings is left out, as inserting a task would change it for every sibling,
+// and so are the fields the server is not told of.
+func hashOf(t task.Task, parent task.ID) string {
+	data, _ := json.Marshal(struct {
+		task.Task
+		Parent task.ID
+	}{format.ICS{}.Fields().Overlay(task.Task{}, t), parent})
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func sortedIDs(m interface{}) []task.ID {
+	ids := []task.ID{}
+	switch m := m.(type) {
+	case map[task.ID]task.Task:
+		for id := range m {
+			ids = append(ids, id)
+		}
+	case map[task.ID]Synced:
+		for id := range m {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
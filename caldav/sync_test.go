@@ -0,0 +1,265 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/td0m/taskman/format"
+	"github.com/td0m/taskman/task"
+)
+
+// server is an in-memory stand-in for a CalDAV server such as Radicale,
+// implementing just enough of it for a sync
+type server struct {
+	mu      sync.Mutex
+	objects map[string]object
+	version int
+	// puts is how many more PUTs succeed, any number if negative
+	puts int
+}
+
+type object struct {
+	data string
+	etag string
+}
+
+var hrefs = regexp.MustCompile(`<d:href>([^<]*)</d:href>`)
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, exists := s.objects[r.URL.Path]
+	switch r.Method {
+	case "PROPFIND":
+		paths := []string{}
+		if r.Header.Get("Depth") == "1" {
+			for path := range s.objects {
+				paths = append(paths, path)
+			}
+		} else if exists {
+			paths = append(paths, r.URL.Path)
+		}
+		s.multistatus(w, paths, false)
+	case "REPORT":
+		body, _ := io.ReadAll(r.Body)
+		paths := []string{}
+		for _, m := range hrefs.FindAllStringSubmatch(string(body), -1) {
+			paths = append(paths, m[1])
+		}
+		s.multistatus(w, paths, true)
+	case "PUT":
+		if s.puts == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.puts--
+		if match := r.Header.Get("If-Match"); match != "" && (!exists || match != current.etag) ||
+			r.Header.Get("If-None-Match") == "*" && exists {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		s.put(r.URL.Path, string(data))
+		w.Header().Set("ETag", s.objects[r.URL.Path].etag)
+		w.WriteHeader(http.StatusCreated)
+	case "DELETE":
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != "" && match != current.etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) put(path, data string) {
+	s.version++
+	s.objects[path] = object{data, fmt.Sprintf(`"%d"`, s.version)}
+}
+
+func (s *server) multistatus(w http.ResponseWriter, paths []string, withData bool) {
+	w.WriteHeader(http.StatusMultiStatus)
+	fmt.Fprint(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+	fmt.Fprint(w, `<d:response><d:href>/tasks/</d:href><d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+	for _, path := range paths {
+		o, ok := s.objects[path]
+		if !ok {
+			continue
+		}
+		data := ""
+		if withData {
+			data = "<c:calendar-data>" + strings.ReplaceAll(o.data, "&", "&amp;") + "</c:calendar-data>"
+		}
+		fmt.Fprintf(w, `<d:response><d:href>%s</d:href><d:propstat><d:prop><d:getetag>%s</d:getetag><d:resourcetype/>%s</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`,
+			path, o.etag, data)
+	}
+	fmt.Fprint(w, `</d:multistatus>`)
+}
+
+// edit changes the title of a task stored on the server, like a phone would
+func (s *server) edit(t *testing.T, id task.ID, title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := "/tasks/" + string(id) + ".ics"
+	todos, err := format.ReadCalendar(strings.NewReader(s.objects[path].data))
+	if err != nil || len(todos) != 1 {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	todos[0].Task.Title = title
+	var b strings.Builder
+	format.WriteCalendar(&b, todos)
+	s.put(path, b.String())
+}
+
+type fixture struct {
+	t      *testing.T
+	server *server
+	client *Client
+	tasks  task.Tasks
+	state  State
+}
+
+func newFixture(t *testing.T) *fixture {
+	s := &server{objects: map[string]object{}, puts: -1}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	tasks := task.NewTasks()
+	tasks.Nodes["a"] = task.Task{Title: "a"}
+	tasks.Nodes["b"] = task.Task{Title: "b"}
+	tasks.Move("a", "root", "", task.Below)
+	tasks.Move("b", "a", "", task.Below)
+	return &fixture{t, s, &Client{URL: ts.URL + "/tasks/"}, tasks, State{Tasks: map[task.ID]Synced{}}}
+}
+
+func (f *fixture) sync(policy Policy) Result {
+	f.t.Helper()
+	res, err := Sync(context.Background(), f.client, f.tasks, f.state, policy)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.state = res.Apply(&f.tasks)
+	if err := f.tasks.Validate(); err != nil {
+		f.t.Fatal(err)
+	}
+	return res
+}
+
+func TestSync(t *testing.T) {
+	f := newFixture(t)
+	if res := f.sync(KeepLocal); res.Pushed != 2 {
+		t.Errorf("first sync pushed %d, want 2", res.Pushed)
+	}
+	if res := f.sync(KeepLocal); res.Pushed != 0 || len(res.Pulled) != 0 {
+		t.Errorf("nothing changed, but got %v", res)
+	}
+
+	// changed on the server
+	f.server.edit(t, "b", "b from phone")
+	if res := f.sync(KeepLocal); len(res.Pulled) != 1 || f.tasks.Nodes["b"].Title != "b from phone" {
+		t.Errorf("got %v, title %q", res, f.tasks.Nodes["b"].Title)
+	}
+	if f.tasks.Parent["b"] != "a" {
+		t.Errorf("pulled task lost its parent")
+	}
+	if res := f.sync(KeepLocal); res.Pushed != 0 || len(res.Pulled) != 0 {
+		t.Errorf("pulled task was synced again: %v", res)
+	}
+
+	// changed on both sides
+	f.server.edit(t, "a", "a from phone")
+	f.tasks.SetTitle("a", "a from desktop")
+	res := f.sync(KeepLocal)
+	if len(res.Conflicts) != 1 || res.Conflicts[0].ID != "a" {
+		t.Errorf("got conflicts %v", res.Conflicts)
+	}
+	if f.tasks.Nodes["a"].Title != "a from desktop" {
+		t.Errorf("local copy should have won, got %q", f.tasks.Nodes["a"].Title)
+	}
+
+	// created on the server
+	var b strings.Builder
+	format.WriteCalendar(&b, []format.Todo{{ID: "c", Task: task.Task{Title: "c"}, Parent: "a", Order: -1}})
+	f.server.put("/tasks/c.ics", b.String())
+	f.sync(KeepLocal)
+	if f.tasks.Parent["c"] != "a" {
+		t.Errorf("new task from the server is under %q, want a", f.tasks.Parent["c"])
+	}
+
+	// deleted here
+	f.tasks.Remove("c")
+	f.sync(KeepLocal)
+	if _, ok := f.server.objects["/tasks/c.ics"]; ok {
+		t.Errorf("task deleted here is still on the server")
+	}
+
+	// deleted on the server
+	delete(f.server.objects, "/tasks/b.ics")
+	f.sync(KeepLocal)
+	if _, ok := f.tasks.Nodes["b"]; ok {
+		t.Errorf("task deleted on the server is still here")
+	}
+}
+
+func TestSync_KeepRemote(t *testing.T) {
+	f := newFixture(t)
+	f.sync(KeepRemote)
+	f.server.edit(t, "a", "a from phone")
+	f.tasks.SetTitle("a", "a from desktop")
+	res := f.sync(KeepRemote)
+	if len(res.Conflicts) != 1 || f.tasks.Nodes["a"].Title != "a from phone" {
+		t.Errorf("got %v, title %q", res, f.tasks.Nodes["a"].Title)
+	}
+}
+
+func TestSync_KeepsLocalFields(t *testing.T) {
+	f := newFixture(t)
+	b := f.tasks.Nodes["b"]
+	b.Status, b.Reminders = "review", []string{"1h before"}
+	f.tasks.Nodes["b"] = b
+	f.sync(KeepLocal)
+
+	f.server.edit(t, "b", "b from phone")
+	f.sync(KeepLocal)
+	if got := f.tasks.Nodes["b"]; got.Title != "b from phone" || got.Status != "review" || len(got.Reminders) != 1 {
+		t.Errorf("got %+v, want the new title and the fields the server does not keep", got)
+	}
+	if res := f.sync(KeepLocal); res.Pushed != 0 || len(res.Pulled) != 0 {
+		t.Errorf("pulled task was synced again: %v", res)
+	}
+}
+
+func TestSync_FailsPartway(t *testing.T) {
+	f := newFixture(t)
+	f.sync(KeepLocal)
+	f.tasks.SetTitle("a", "a from desktop")
+	f.tasks.SetTitle("b", "b from desktop")
+
+	// a is pushed, b is not
+	f.server.puts = 1
+	res, err := Sync(context.Background(), f.client, f.tasks, f.state, KeepLocal)
+	if err == nil {
+		t.Fatal("the second push should fail")
+	}
+	if len(res.State.Tasks) != 2 || res.State.Tasks["a"] == f.state.Tasks["a"] || res.State.Tasks["b"] != f.state.Tasks["b"] {
+		t.Errorf("got state %v, want a as pushed and b as it was", res.State.Tasks)
+	}
+	f.state = res.State
+
+	f.server.puts = -1
+	if res := f.sync(KeepLocal); len(res.Conflicts) != 0 || res.Pushed != 1 {
+		t.Errorf("got %v, want b pushed and no conflicts", res)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/td0m/taskman/task"
+	"github.com/td0m/taskman/ui"
+)
+
+// calendar is the state of the calendar view, where the visible tasks are
+// the ones due on the selected day
+type calendar struct {
+	// day is the selected day, kept as midnight UTC like due dates
+	day time.Time
+	// dragging is the task being dragged with the mouse, onto drop
+	dragging task.ID
+	drop     time.Time
+}
+
+var (
+	calendarToday   = lipgloss.NewStyle().Bold(true).Foreground(ui.Primary)
+	calendarOutside = lipgloss.NewStyle().Foreground(ui.Faded)
+	calendarDay     = lipgloss.NewStyle().Foreground(ui.Secondary)
+	calendarDrop    = lipgloss.NewStyle().Background(ui.Secondary).Foreground(ui.Background)
+	overflow        = lipgloss.NewStyle().Foreground(ui.Faded)
+)
+
+// dayOf is the day of t, as midnight UTC
+func dayOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// addMonths moves day by n months, keeping to the last day of shorter months
+func addMonths(day time.Time, n int) time.Time {
+	first := time.Date(day.Year(), day.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day.Day(), last)-1)
+}
+
+// monthGrid returns the monday the grid of the month of day starts on, and
+// how many weeks it spans
+func monthGrid(day time.Time) (time.Time, int) {
+	first := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	// weeks start on monday
+	offset := (int(first.Weekday()) + 6) % 7
+	days := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, -offset), (offset + days + 6) / 7
+}
+
+// cellSize is the width and height of a day, the weekday names taking a line
+func (m app) cellSize() (int, int) {
+	_, weeks := monthGrid(m.calendar.day)
+	return m.viewport.Width / 7, max((m.viewport.Height-1)/weeks, 2)
+}
+
+// dayAt returns the day under a point of the viewport, and the line of its
+// cell the point is on
+func (m app) dayAt(x, y int) (time.Time, int, bool) {
+	first, weeks := monthGrid(m.calendar.day)
+	w, h := m.cellSize()
+	if x < 0 || y < 1 || w == 0 {
+		return time.Time{}, 0, false
+	}
+	col, row := x/w, (y-1)/h
+	if col >= 7 || row >= weeks {
+		return time.Time{}, 0, false
+	}
+	return first.AddDate(0, 0, row*7+col), (y - 1) % h, true
+}
+
+// byDay groups the dated tasks by the day they are due
+func (m *app) byDay() map[time.Time][]path {
+	days := map[time.Time][]path{}
+	for _, p := range m.dated() {
+		day := dayOf(*m.all.Nodes[getID(p)].Due)
+		days[day] = append(days[day], p)
+	}
+	return days
+}
+
+// window returns which of the n tasks due on day fit in its cell, scrolled to
+// the cursor on the selected day
+func (m app) window(day time.Time, n int) (int, int) {
+	_, h := m.cellSize()
+	cursor := -1
+	if day.Equal(m.calendar.day) {
+		cursor = m.cursor
+	}
+	return scroll(n, h-1, cursor)
+}
+
+// selectDay moves the calendar to day, selecting its first task
+func (m *app) selectDay(day time.Time) {
+	m.calendar.day = dayOf(day)
+	m.updateVisible()
+	m.setCursor(0)
+}
+
+// reschedule moves a task to another day, and the calendar along with it
+func (m *app) reschedule(id task.ID, due time.Time) error {
+	if err := m.all.SetDue(id, &due); err != nil {
+		return err
+	}
+	m.selectDay(due)
+	m.setCursor(max(m.indexOf(id), 0))
+	return nil
+}
+
+// calendarKeys are the keys that differ in the calendar view
+var calendarKeys = []viewBinding{
+	{binding{[]string{"h", "l"}, "day", "select the previous or next day"}, func(m *app, key string) error {
+		by := 1
+		if key == "h" {
+			by = -1
+		}
+		m.selectDay(m.calendar.day.AddDate(0, 0, by))
+		return nil
+	}},
+	{binding{[]string{"k", "j"}, "week", "select the same day a week before or after"}, func(m *app, key string) error {
+		by := 7
+		if key == "k" {
+			by = -7
+		}
+		m.selectDay(m.calendar.day.AddDate(0, 0, by))
+		return nil
+	}},
+	{binding{[]string{"[", "]"}, "month", "show the previous or next month"}, func(m *app, key string) error {
+		by := 1
+		if key == "[" {
+			by = -1
+		}
+		m.selectDay(addMonths(m.calendar.day, by))
+		return nil
+	}},
+	{binding{[]string{"H", "L", "K", "J"}, "reschedule", "move the task by a day or a week"}, func(m *app, key string) error {
+		shifts := map[string]int{"H": -1, "L": 1, "K": -7, "J": 7}
+		if id := getID(m.atCursor()); len(id) > 0 {
+			return m.reschedule(id, m.calendar.day.AddDate(0, 0, shifts[key]))
+		}
+		return nil
+	}},
+	{binding{[]string{"tab", "shift+tab"}, "select", "select the next or previous task of the day"}, func(m *app, key string) error {
+		by := 1
+		if key == "shift+tab" {
+			by = -1
+		}
+		m.setCursor(m.cursor + by)
+		return nil
+	}},
+	{binding{[]string{"o", "O"}, "new", "add a task due on the selected day"}, func(m *app, key string) error {
+		id := getID(m.atCursor())
+		parent, pos := task.ID("root"), task.Below
+		if key == "O" {
+			pos = task.Above
+		}
+		if len(id) > 0 {
+			parent = m.all.Parent[id]
+		}
+		added, err := m.all.Add(parent, id, pos)
+		if err != nil {
+			return err
+		}
+		if err := m.reschedule(added, m.calendar.day); err != nil {
+			return err
+		}
+		m.edit()
+		return nil
+	}},
+}
+
+// calendarMouse selects the day and task clicked on, and reschedules tasks
+// dragged onto another day
+func (m *app) calendarMouse(msg tea.MouseMsg) error {
+	day, line, ok := m.dayAt(msg.X, msg.Y-headerHeight)
+	switch msg.Type {
+	case tea.MouseWheelUp:
+		m.selectDay(addMonths(m.calendar.day, -1))
+	case tea.MouseWheelDown:
+		m.selectDay(addMonths(m.calendar.day, 1))
+	case tea.MouseLeft:
+		if !ok {
+			return nil
+		}
+		// holding the button down reports more presses as the mouse moves
+		if len(m.calendar.dragging) > 0 {
+			m.calendar.drop = day
+			return nil
+		}
+		m.selectDay(day)
+		start, end := m.window(day, len(m.visible))
+		if i := start + line - 1; line > 0 && i < end {
+			m.setCursor(i)
+			m.calendar.dragging, m.calendar.drop = getID(m.atCursor()), day
+		}
+	case tea.MouseRelease:
+		id, drop := m.calendar.dragging, m.calendar.drop
+		m.calendar.dragging = ""
+		if len(id) == 0 || drop.Equal(m.calendar.day) {
+			return nil
+		}
+		return m.reschedule(id, drop)
+	}
+	return nil
+}
+
+// renderCalendar renders the month of the selected day as a grid
+func (m app) renderCalendar() string {
+	first, weeks := monthGrid(m.calendar.day)
+	w, h := m.cellSize()
+	if w < 2 {
+		return ""
+	}
+	fit := func(s string) string {
+		return runewidth.FillRight(runewidth.Truncate(s, w-1, "…"), w-1) + " "
+	}
+
+	s := ""
+	for i := 0; i < 7; i++ {
+		s += ui.RenderSection(fit(first.AddDate(0, 0, i).Weekday().String()[:3]))
+	}
+	s += "\n"
+
+	today := dayOf(time.Now().Truncate(time.Hour * 24))
+	days := m.byDay()
+	for week := 0; week < weeks; week++ {
+		lines := make([]string, h)
+		for i := 0; i < 7; i++ {
+			day := first.AddDate(0, 0, week*7+i)
+			paths := days[day]
+			selected := day.Equal(m.calendar.day)
+
+			number := strconv.Itoa(day.Day())
+			style := calendarDay
+			switch {
+			case len(m.calendar.dragging) > 0 && day.Equal(m.calendar.drop):
+				style = calendarDrop
+			case selected && m.mode == normalMode:
+				style = lipgloss.NewStyle().Background(ui.Faded).Foreground(ui.Background)
+			case day.Equal(today):
+				style = calendarToday
+			case day.Month() != m.calendar.day.Month():
+				style = calendarOutside
+			}
+			if day.Equal(today) {
+				number += " today"
+			}
+			lines[0] += style.Render(fit(number))
+
+			start, end := m.window(day, len(paths))
+			for l := 1; l < h; l++ {
+				j := start + l - 1
+				switch {
+				case j < end:
+					t := m.all.Nodes[getID(paths[j])]
+					title := ui.Title(t)
+					if selected && j == m.cursor && m.mode == normalMode {
+						title = title.Copy().Background(ui.Faded).Foreground(ui.Background)
+					}
+					lines[l] += title.Render(fit(t.Title))
+				case l == end-start+1 && len(paths) > end-start:
+					lines[l] += overflow.Render(fit("+" + strconv.Itoa(len(paths)-end+start) + " more"))
+				default:
+					lines[l] += fit("")
+				}
+			}
+		}
+		s += strings.Join(lines, "\n") + "\n"
+	}
+	return s
+}
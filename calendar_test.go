@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestMonthGrid(t *testing.T) {
+	tests := []struct {
+		day   time.Time
+		first time.Time
+		weeks int
+	}{
+		// starts on a thursday
+		{date(2026, 10, 19), date(2026, 9, 28), 5},
+		// starts on a monday, and fits four weeks
+		{date(2027, 2, 10), date(2027, 2, 1), 4},
+		// starts on a sunday
+		{date(2026, 11, 30), date(2026, 10, 26), 6},
+	}
+	for _, tt := range tests {
+		first, weeks := monthGrid(tt.day)
+		if !first.Equal(tt.first) || weeks != tt.weeks {
+			t.Errorf("%v: got %v and %d weeks, want %v and %d weeks", tt.day, first, weeks, tt.first, tt.weeks)
+		}
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	tests := []struct {
+		day  time.Time
+		n    int
+		want time.Time
+	}{
+		{date(2026, 10, 19), 1, date(2026, 11, 19)},
+		{date(2027, 1, 31), 1, date(2027, 2, 28)},
+		{date(2026, 3, 31), -1, date(2026, 2, 28)},
+		{date(2026, 12, 15), 1, date(2027, 1, 15)},
+	}
+	for _, tt := range tests {
+		if got := addMonths(tt.day, tt.n); !got.Equal(tt.want) {
+			t.Errorf("%v + %d months: got %v, want %v", tt.day, tt.n, got, tt.want)
+		}
+	}
+}
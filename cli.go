@@ -0,0 +1,371 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/td0m/taskman/api"
+	"github.com/td0m/taskman/format"
+	"github.com/td0m/taskman/remind"
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+// command is a non-interactive subcommand, run as `taskman <name> [flags]`
+type command struct {
+	usage string
+	run   func(store *storage.JSONBackend, args []string, out io.Writer) error
+}
+
+var commands = map[string]command{
+	"add":      {"add [--parent <id>] [--due <date>] <title>", addCommand},
+	"fsck":     {"fsck [--fix]", fsck},
+	"import":   {"import --format <format> [--parent <id>] [file]", importTasks},
+	"export":   {"export --format <format> [--root <id>] [file]", exportTasks},
+	"sync":     {"sync", syncTasks},
+	"serve":    {"serve [--listen <addr>] [--allow-origin <origin>]", serve},
+	"remind":   {"remind [--notify <notifier>] [--once]", remindTasks},
+	"snooze":   {"snooze <id> [duration]", snoozeTask},
+	"statuses": {"statuses [<status>...]", statuses},
+	"report":   {"report [--by day|week] [--periods <n>] [--root <id>] [--format text|csv|json] [--table periods|projects]", reportCommand},
+}
+
+var errProblems = errors.New("task graph is inconsistent, run with --fix to repair it")
+
+func fsck(store *storage.JSONBackend, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	fix := flags.Bool("fix", false, "repair the problems that were found")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	tasks, err := store.Fetch()
+	if errors.Is(err, storage.ErrCorrupt) && *fix {
+		fmt.Fprintln(out, err)
+		tasks, err = store.Repair()
+	}
+	if err != nil {
+		return err
+	}
+
+	if !*fix {
+		err := tasks.Validate()
+		var problems task.Problems
+		if errors.As(err, &problems) {
+			fmt.Fprintln(out, problems)
+			return errProblems
+		}
+		return err
+	}
+
+	problems := tasks.Fix()
+	if len(problems) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out, problems)
+	if _, err := store.Sync(tasks); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "fixed %d problems\n", len(problems))
+	return nil
+}
+
+func importTasks(store *storage.JSONBackend, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	codec := formatFlag(flags)
+	parent := flags.String("parent", "root", "ID of the task to import under")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *codec == nil {
+		return errNoFormat
+	}
+
+	in := io.Reader(os.Stdin)
+	if flags.NArg() > 0 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	imported, err := (*codec).Decode(in)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := store.Fetch()
+	if err != nil {
+		return err
+	}
+	added, updated, err := tasks.Merge(imported, task.ID(*parent), (*codec).Fields())
+	if err != nil {
+		return err
+	}
+	if _, err := store.Sync(tasks); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "added %d, updated %d\n", added, updated)
+	return nil
+}
+
+// addCommand hands the task over to the TUI if one is open, and writes the
+// task file itself otherwise
+func addCommand(store *storage.JSONBackend, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("add", flag.ContinueOnError)
+	parent := flags.String("parent", "root", "ID of the task to add under")
+	due := flags.String("due", "", "due date, such as 2026-10-20")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	req := controlRequest{Cmd: "add", Parent: task.ID(*parent), Title: strings.Join(flags.Args(), " "), Due: *due}
+
+	id, err := sendControl(store, req)
+	if errors.Is(err, errNotRunning) {
+		var tasks task.Tasks
+		if tasks, err = store.Fetch(); err != nil {
+			return err
+		}
+		if id, err = addTask(&tasks, req); err != nil {
+			return err
+		}
+		_, err = store.Sync(tasks)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, id)
+	return nil
+}
+
+func exportTasks(store *storage.JSONBackend, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	codec := formatFlag(flags)
+	root := flags.String("root", "root", "ID of the task whose subtree is exported")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *codec == nil {
+		return errNoFormat
+	}
+
+	tasks, err := store.Fetch()
+	if err != nil {
+		return err
+	}
+	if _, found := tasks.Nodes[task.ID(*root)]; !found {
+		return task.ErrBadID
+	}
+	if flags.NArg() > 0 {
+		f, err := os.Create(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	return (*codec).Encode(out, tasks, task.ID(*root))
+}
+
+func syncTasks(store *storage.JSONBackend, args []string, out io.Writer) error {
+	s, err := newSyncer(store)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return errors.New("TASKMAN_CALDAV_URL is not set")
+	}
+	tasks, err := store.Fetch()
+	if err != nil {
+		return err
+	}
+	msg := s.sync(tasks)().(syncDoneMsg)
+	if msg.err != nil {
+		// what was pushed before it failed is not pushed again as new
+		if msg.res.State.Tasks != nil {
+			if err := msg.res.State.Save(s.state); err != nil {
+				return fmt.Errorf("%w, and saving what was pushed: %v", msg.err, err)
+			}
+		}
+		return msg.err
+	}
+	state := msg.res.Apply(&tasks)
+	if _, err := store.Sync(tasks); err != nil {
+		return err
+	}
+	for _, c := range msg.res.Conflicts {
+		fmt.Fprintln(out, "conflict:", c)
+	}
+	fmt.Fprintln(out, msg.res)
+	return state.Save(s.state)
+}
+
+func serve(store *storage.JSONBackend, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := flags.String("listen", "127.0.0.1:7405", "address to serve the API on")
+	origin := flags.String("allow-origin", "", "origin allowed to call the API from a browser, such as http://localhost:3000")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	server := api.New(store)
+	server.AllowOrigin = *origin
+	server.Hosts = hosts(*listen, l.Addr())
+	server.Forward = forward(store)
+	fmt.Fprintf(out, "serving %s on http://%s/tasks\n", store.File(), l.Addr())
+	return http.Serve(l, server)
+}
+
+// hosts lists the Host headers a server listening on addr answers to: the
+// address as given and as bound, and localhost for a loopback address. A server
+// listening on every interface answers to names that cannot be known, and to
+// any host.
+func hosts(listen string, addr net.Addr) []string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok || tcp.IP.IsUnspecified() {
+		return nil
+	}
+	hosts := []string{listen, tcp.String()}
+	if tcp.IP.IsLoopback() {
+		hosts = append(hosts, net.JoinHostPort("localhost", fmt.Sprint(tcp.Port)))
+	}
+	return hosts
+}
+
+// remindTasks sends reminders until interrupted, reading the task file anew
+// for every check
+func remindTasks(store *storage.JSONBackend, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("remind", flag.ContinueOnError)
+	notify := flags.String("notify", "notify-send", "notify-send, bell, or a shell command")
+	once := flags.Bool("once", false, "check once and exit, as from cron")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	r := &reminder{remind.NewNotifier(*notify), remindState(store)}
+	for {
+		tasks, err := store.Fetch()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		alerts, err := r.send(tasks, now)
+		for _, a := range alerts {
+			fmt.Fprintf(out, "%s %s: %s\n", now.Format("15:04"), a.Task.Title, a.Message(now))
+		}
+		if err != nil {
+			return err
+		}
+		if *once {
+			return nil
+		}
+		time.Sleep(remindInterval)
+	}
+}
+
+func snoozeTask(store *storage.JSONBackend, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("snooze", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 {
+		return errors.New("missing task ID")
+	}
+	d := time.Hour
+	if flags.NArg() > 1 {
+		var err error
+		if d, err = remind.ParseDuration(flags.Arg(1)); err != nil {
+			return err
+		}
+	}
+	tasks, err := store.Fetch()
+	if err != nil {
+		return err
+	}
+	id := task.ID(flags.Arg(0))
+	if _, found := tasks.Nodes[id]; !found {
+		return task.ErrBadID
+	}
+	now := time.Now()
+	until := now.Add(d)
+	if err := snooze(store, id, until); err != nil {
+		return err
+	}
+	layout := "15:04"
+	if y, m, day := until.Date(); y != now.Year() || m != now.Month() || day != now.Day() {
+		layout = "Mon Jan 2 15:04"
+	}
+	fmt.Fprintln(out, "snoozed until", until.Format(layout))
+	return nil
+}
+
+// statuses prints the workflow of the board, or replaces it
+func statuses(store *storage.JSONBackend, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("statuses", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	tasks, err := store.Fetch()
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(out, strings.Join(tasks.Workflow(), "\n"))
+		return nil
+	}
+	if err := tasks.SetWorkflow(flags.Args()); err != nil {
+		return err
+	}
+	_, err = store.Sync(tasks)
+	return err
+}
+
+var errNoFormat = errors.New("missing --format")
+
+// formatFlag adds a --format flag that picks one of format.Codecs
+func formatFlag(flags *flag.FlagSet) *format.Codec {
+	var codec format.Codec
+	names := []string{}
+	for name := range format.Codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	flags.Func("format", "one of: "+strings.Join(names, ", "), func(s string) error {
+		c, ok := format.Codecs[s]
+		if !ok {
+			return fmt.Errorf("unknown format %q", s)
+		}
+		codec = c
+		return nil
+	})
+	return &codec
+}
+
+func usage(out io.Writer) {
+	fmt.Fprintln(out, "usage: taskman [command]")
+	fmt.Fprintln(out, "\nwith no command, opens the task list\n\ncommands:")
+	for _, name := range sortedCommands() {
+		fmt.Fprintln(out, "  taskman", commands[name].usage)
+	}
+}
+
+func sortedCommands() []string {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/td0m/taskman/api"
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+// control is the Unix socket a running TUI listens on, next to the task file,
+// so that other processes can change its tasks instead of racing it for the
+// file. Each connection sends one request as JSON and gets one reply:
+//
+//	{"cmd": "add", "title": "buy milk", "parent": "<id>", "due": "2026-10-20"}
+//	{"cmd": "done", "id": "<id>"}
+//	{"cmd": "jump", "id": "<id>"}
+//	{"cmd": "reload"}
+//	{"cmd": "replace", "base": "<hash>", "tasks": {...}}
+//
+// replace is how the API hands over its changes: the tasks replace those of the
+// TUI, as long as the hash of the TUI's, see storage.Hash, is still base.
+type control struct {
+	listener net.Listener
+	msgs     chan controlMsg
+}
+
+type controlRequest struct {
+	Cmd    string  `json:"cmd"`
+	ID     task.ID `json:"id,omitempty"`
+	Parent task.ID `json:"parent,omitempty"`
+	Title  string  `json:"title,omitempty"`
+	Due    string  `json:"due,omitempty"`
+	// Base and Tasks are those of replace
+	Base  string      `json:"base,omitempty"`
+	Tasks *task.Tasks `json:"tasks,omitempty"`
+}
+
+type controlReply struct {
+	ID    task.ID `json:"id,omitempty"`
+	Error string  `json:"error,omitempty"`
+}
+
+// controlMsg is a request delivered into the update loop, which answers it on reply
+type controlMsg struct {
+	req   controlRequest
+	reply chan<- controlReply
+}
+
+var (
+	errNotRunning  = errors.New("taskman is not running")
+	errAlreadyOpen = errors.New("taskman is already open")
+	errUnknownCmd  = errors.New("unknown command")
+	errEmptyTitle  = errors.New("title is empty")
+	controlTimeout = 10 * time.Second
+)
+
+func socketPath(store *storage.JSONBackend) string {
+	return store.File() + ".sock"
+}
+
+// listenControl starts listening on the socket of store. It fails if another
+// TUI is already open on the same file, with errAlreadyOpen.
+func listenControl(store *storage.JSONBackend) (*control, error) {
+	path := socketPath(store)
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w on %s", errAlreadyOpen, store.File())
+	}
+	// left behind by a TUI that did not exit cleanly
+	os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	c := &control{l, make(chan controlMsg)}
+	go c.accept()
+	return c, nil
+}
+
+func (c *control) accept() {
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			return
+		}
+		go c.serve(conn)
+	}
+}
+
+func (c *control) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+	var req controlRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(controlReply{Error: err.Error()})
+		return
+	}
+	reply := make(chan controlReply, 1)
+	select {
+	case c.msgs <- controlMsg{req, reply}:
+	case <-time.After(controlTimeout):
+		json.NewEncoder(conn).Encode(controlReply{Error: "taskman is busy"})
+		return
+	}
+	select {
+	case r := <-reply:
+		json.NewEncoder(conn).Encode(r)
+	case <-time.After(controlTimeout):
+		json.NewEncoder(conn).Encode(controlReply{Error: "taskman did not answer"})
+	}
+}
+
+// wait delivers the next request into the update loop
+func (c *control) wait() tea.Cmd {
+	return func() tea.Msg {
+		return <-c.msgs
+	}
+}
+
+// Close stops listening and removes the socket
+func (c *control) Close() error {
+	return c.listener.Close()
+}
+
+// sendControl sends req to the TUI open on store, failing with errNotRunning
+// if there is none
+func sendControl(store *storage.JSONBackend, req controlRequest) (task.ID, error) {
+	conn, err := net.DialTimeout("unix", socketPath(store), time.Second)
+	if err != nil {
+		return "", errNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return "", err
+	}
+	var reply controlReply
+	if err := json.NewDecoder(conn).Decode(&reply); err != nil {
+		return "", err
+	}
+	if reply.Error != "" {
+		return reply.ID, errors.New(reply.Error)
+	}
+	return reply.ID, nil
+}
+
+// addTask adds the task described by an add request at the bottom of its parent
+func addTask(tasks *task.Tasks, req controlRequest) (task.ID, error) {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return "", errEmptyTitle
+	}
+	parent := req.Parent
+	if parent == "" {
+		parent = "root"
+	}
+	var due *time.Time
+	if req.Due != "" {
+		d, err := time.Parse("2006-01-02", req.Due)
+		if err != nil {
+			return "", fmt.Errorf("due: %w", err)
+		}
+		due = &d
+	}
+	id, err := tasks.Add(parent, "", task.Below)
+	if err != nil {
+		return "", err
+	}
+	tasks.SetTitle(id, title)
+	tasks.SetDue(id, due)
+	return id, nil
+}
+
+// forward hands the changes of the API over to the TUI open on store, if any
+func forward(store *storage.JSONBackend) func(before, after task.Tasks) error {
+	return func(before, after task.Tasks) error {
+		_, err := sendControl(store, controlRequest{Cmd: "replace", Base: storage.Hash(before), Tasks: &after})
+		switch {
+		case errors.Is(err, errNotRunning):
+			return api.ErrNotForwarded
+		// the reply only has the message
+		case err != nil && err.Error() == api.ErrStale.Error():
+			return api.ErrStale
+		}
+		return err
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/td0m/taskman/api"
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+func TestAddTask(t *testing.T) {
+	tasks := task.NewTasks()
+	parent, _ := tasks.Add("root", "", task.Below)
+	tests := []struct {
+		req controlRequest
+		err error
+	}{
+		{controlRequest{Title: " buy milk "}, nil},
+		{controlRequest{Title: "sub", Parent: parent, Due: "2026-10-20"}, nil},
+		{controlRequest{Title: "  "}, errEmptyTitle},
+		{controlRequest{Title: "x", Parent: "nope"}, task.ErrNoParent},
+	}
+	for _, tt := range tests {
+		id, err := addTask(&tasks, tt.req)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%+v: got %v, want %v", tt.req, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		want := tt.req.Parent
+		if want == "" {
+			want = "root"
+		}
+		if got := tasks.Nodes[id]; tasks.Parent[id] != want || got.Title != strings.TrimSpace(tt.req.Title) {
+			t.Errorf("%+v: added %+v under %s", tt.req, got, tasks.Parent[id])
+		}
+		if tt.req.Due != "" && tasks.Nodes[id].Due.Format("2006-01-02") != tt.req.Due {
+			t.Errorf("%+v: due %v", tt.req, tasks.Nodes[id].Due)
+		}
+	}
+	if _, err := addTask(&tasks, controlRequest{Title: "x", Due: "friday"}); err == nil {
+		t.Errorf("a due date that is not one should fail")
+	}
+}
+
+func TestHandle(t *testing.T) {
+	store := storage.NewJSON(filepath.Join(t.TempDir(), "tasks.json"))
+	tasks, _ := store.Fetch()
+	m := newApp(store, tasks)
+
+	id, err := m.handle(controlRequest{Cmd: "add", Title: "release"})
+	if err != nil || m.indexOf(id) < 0 {
+		t.Fatalf("add: %v, shown at %d", err, m.indexOf(id))
+	}
+	if saved, _ := store.Fetch(); saved.Nodes[id].Title != "release" {
+		t.Errorf("added task not saved")
+	}
+	if _, err := m.handle(controlRequest{Cmd: "done", ID: id}); err != nil || m.all.Nodes[id].Done == nil {
+		t.Errorf("done: %v", err)
+	}
+	if _, err := m.handle(controlRequest{Cmd: "done", ID: "nope"}); !errors.Is(err, task.ErrBadID) {
+		t.Errorf("done of a missing task: got %v", err)
+	}
+	if _, err := m.handle(controlRequest{Cmd: "frobnicate"}); !errors.Is(err, errUnknownCmd) {
+		t.Errorf("got %v, want %v", err, errUnknownCmd)
+	}
+
+	// changes made to the file elsewhere are picked up on reload
+	saved, _ := store.Fetch()
+	other, _ := saved.Add("root", "", task.Below)
+	saved.SetTitle(other, "elsewhere")
+	store.Sync(saved)
+	if _, err := m.handle(controlRequest{Cmd: "reload"}); err != nil || m.indexOf(other) < 0 {
+		t.Errorf("reload: %v, shown at %d", err, m.indexOf(other))
+	}
+
+	// nothing changes while a failure has not been dismissed
+	m.err = errors.New("boom")
+	if _, err := m.handle(controlRequest{Cmd: "add", Title: "later"}); err == nil {
+		t.Errorf("add after a failure should be refused")
+	}
+}
+
+func TestHandleReplace(t *testing.T) {
+	store := storage.NewJSON(filepath.Join(t.TempDir(), "tasks.json"))
+	tasks, _ := store.Fetch()
+	m := newApp(store, tasks)
+
+	base := storage.Hash(m.all)
+	changed := m.all.Clone()
+	id, _ := changed.Add("root", "", task.Below)
+	changed.SetTitle(id, "from the API")
+	if _, err := m.handle(controlRequest{Cmd: "replace", Base: base, Tasks: &changed}); err != nil || m.indexOf(id) < 0 {
+		t.Fatalf("replace: %v, shown at %d", err, m.indexOf(id))
+	}
+
+	// the API read the tasks before the last change made here
+	if _, err := m.handle(controlRequest{Cmd: "replace", Base: base, Tasks: &changed}); !errors.Is(err, api.ErrStale) {
+		t.Errorf("got %v, want %v", err, api.ErrStale)
+	}
+
+	// what is typed in the TUI is saved, so the API reads it back from the file
+	keys := []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("o")},
+		{Type: tea.KeyRunes, Runes: []rune("typed")},
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyRunes, Runes: []rune("d")},
+		{Type: tea.KeyRunes, Runes: []rune("fri")},
+		{Type: tea.KeyEnter},
+	}
+	for _, k := range keys {
+		model, _ := m.Update(k)
+		m = model.(app)
+	}
+	saved, _ := store.Fetch()
+	if id := getID(m.atCursor()); saved.Nodes[id].Title != "typed" || saved.Nodes[id].Due == nil {
+		t.Fatalf("not saved: %+v", saved.Nodes[id])
+	}
+	changed = saved.Clone()
+	if _, err := m.handle(controlRequest{Cmd: "replace", Base: storage.Hash(saved), Tasks: &changed}); err != nil {
+		t.Errorf("replace after typing: %v", err)
+	}
+}
+
+func TestJump(t *testing.T) {
+	store := storage.NewJSON(filepath.Join(t.TempDir(), "tasks.json"))
+	tasks, _ := store.Fetch()
+	release, _ := tasks.Add("root", "", task.Below)
+	notes, _ := tasks.Add(release, "", task.Below)
+	chores, _ := tasks.Add("root", "", task.Below)
+	old, _ := tasks.Add("root", "", task.Below)
+	long := time.Now().AddDate(0, -1, 0)
+	tasks.SetDone(old, &long)
+	tasks.SetFolded(release, true)
+
+	m := newApp(store, tasks)
+	// notes is folded away, outside of the hoisted task, in a tab that shows
+	// it; then in one that does not
+	for _, tab := range []int{1, 2} {
+		m.showTab(tab)
+		m.hoist(chores)
+		if err := m.jump(notes); err != nil {
+			t.Fatalf("tab %d: %v", tab, err)
+		}
+		if got := getID(m.atCursor()); got != notes || m.root() != "root" || m.folded(release) {
+			t.Errorf("tab %d: cursor on %s under %s", tab, got, m.root())
+		}
+	}
+	if got := m.tabs.Value(); got != 0 {
+		t.Errorf("switched to tab %d, want the first", got)
+	}
+
+	if err := m.jump(old); err == nil {
+		t.Errorf("jumping to a task no tab shows should fail")
+	}
+	if err := m.jump("nope"); !errors.Is(err, task.ErrBadID) {
+		t.Errorf("got %v, want %v", err, task.ErrBadID)
+	}
+}
+
+func TestControlPanic(t *testing.T) {
+	// there is no store to reload from
+	m := newApp(nil, task.NewTasks())
+	m.control = &control{msgs: make(chan controlMsg)}
+	reply := make(chan controlReply, 1)
+	m.Update(controlMsg{controlRequest{Cmd: "reload"}, reply})
+	select {
+	case r := <-reply:
+		if r.Error == "" {
+			t.Errorf("got no error")
+		}
+	default:
+		t.Fatalf("the request was not answered")
+	}
+}
+
+func TestListenControl(t *testing.T) {
+	store := storage.NewJSON(filepath.Join(t.TempDir(), "tasks.json"))
+	c, err := listenControl(store)
+	if err != nil {
+		t.Skip("no unix sockets here:", err)
+	}
+	if _, err := listenControl(store); !errors.Is(err, errAlreadyOpen) {
+		t.Errorf("got %v with a TUI open, want %v", err, errAlreadyOpen)
+	}
+
+	// once it is closed, the next one can take its place
+	c.Close()
+	if c, err = listenControl(store); err != nil {
+		t.Errorf("got %v after the TUI closed", err)
+	} else {
+		c.Close()
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/td0m/taskman/pkg/dateinput"
+	"github.com/td0m/taskman/task"
+)
+
+// filterHelp sums up the terms of a filter, for when one cannot be made sense of
+const filterHelp = "filters are made of open, done, recent:<days>, undated, due:<date>, #tag, !<priority> and words of the title, each negated by a leading -, or - alone for none"
+
+// parseFilter reads the filter of a view: terms separated by spaces, all of
+// which a task has to match. Dates are typed as into the date input, with
+// underscores for spaces, such as due:next_fri.
+func parseFilter(s string, now time.Time) (predicate, error) {
+	today := now.Truncate(time.Hour * 24)
+	keep := []predicate{}
+	for _, term := range strings.Fields(s) {
+		negated := strings.HasPrefix(term, "-") && len(term) > 1
+		if negated {
+			term = term[1:]
+		}
+		f, err := parseTerm(term, today)
+		if err != nil {
+			return nil, err
+		}
+		if negated {
+			f = not(f)
+		}
+		keep = append(keep, f)
+	}
+	return func(t task.Task) bool {
+		for _, f := range keep {
+			if !f(t) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func parseTerm(term string, today time.Time) (predicate, error) {
+	key, value := term, ""
+	if i := strings.IndexByte(term, ':'); i >= 0 {
+		key, value = term[:i], term[i+1:]
+	}
+	switch {
+	case term == "open":
+		return func(t task.Task) bool { return t.Done == nil }, nil
+	case term == "done":
+		return func(t task.Task) bool { return t.Done != nil }, nil
+	case term == "undated":
+		return func(t task.Task) bool { return t.Due == nil }, nil
+	case key == "recent":
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return nil, badArgs("recent takes a number of days: " + term)
+		}
+		since := today.AddDate(0, 0, -days)
+		return func(t task.Task) bool { return t.Done == nil || t.Done.After(since) }, nil
+	case key == "due":
+		day := dateinput.Parse(strings.ReplaceAll(value, "_", " "))
+		if day == nil {
+			return nil, badArgs("not a date: " + term)
+		}
+		end := day.Truncate(time.Hour*24).AddDate(0, 0, 1)
+		return func(t task.Task) bool { return t.Due != nil && t.Due.Before(end) }, nil
+	case strings.HasPrefix(term, "#") && len(term) > 1:
+		return func(t task.Task) bool {
+			for _, tag := range t.Tags {
+				if strings.EqualFold(tag, term[1:]) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case strings.HasPrefix(term, "!") && len(term) == 2:
+		p := strings.ToUpper(term[1:])
+		return func(t task.Task) bool { return t.Priority != "" && t.Priority <= p }, nil
+	case value != "":
+		return nil, badArgs(filterHelp)
+	}
+	word := strings.ToLower(term)
+	return func(t task.Task) bool { return strings.Contains(strings.ToLower(t.Title), word) }, nil
+}
+
+func not(f predicate) predicate {
+	return func(t task.Task) bool { return !f(t) }
+}
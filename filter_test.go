@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+func TestParseFilter(t *testing.T) {
+	now := time.Now()
+	day := func(d int) *time.Time {
+		t := now.Truncate(time.Hour*24).AddDate(0, 0, d)
+		return &t
+	}
+	tasks := map[string]task.Task{
+		"old":     {Title: "old", Done: day(-9)},
+		"done":    {Title: "done", Done: day(0)},
+		"late":    {Title: "Late report", Due: day(-1), Priority: "B"},
+		"later":   {Title: "later", Due: day(3), Tags: []string{"Work"}},
+		"undated": {Title: "undated", Priority: "A", Tags: []string{"home"}},
+	}
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{"", "done late later old undated"},
+		{"recent:5", "done late later undated"},
+		{"undated", "done old undated"},
+		{"due:today", "late"},
+		{"due:in_3_days open", "late later"},
+		{"#work", "later"},
+		{"-#work open", "late undated"},
+		{"!b", "late undated"},
+		{"report", "late"},
+		{"due:someday", ""},
+		{"size:big", ""},
+	}
+	for _, tt := range tests {
+		keep, err := parseFilter(tt.filter, now)
+		if err != nil {
+			if tt.want != "" {
+				t.Errorf("%q: %v", tt.filter, err)
+			}
+			continue
+		}
+		got := []string{}
+		for _, name := range []string{"done", "late", "later", "old", "undated"} {
+			if keep(tasks[name]) {
+				got = append(got, name)
+			}
+		}
+		if tt.want == "" {
+			t.Errorf("%q: got %v, want an error", tt.filter, got)
+			continue
+		}
+		if g := strings.Join(got, " "); g != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.filter, g, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package format converts task lists to and from the formats used by other tools.
+package format
+
+import (
+	"io"
+
+	"github.com/td0m/taskman/task"
+)
+
+// Codec reads and writes task lists in a single format
+type Codec interface {
+	// Encode writes every descendant of root
+	Encode(w io.Writer, tasks task.Tasks, root task.ID) error
+	// Decode reads a task list, placing its top level tasks under "root".
+	// Tasks keep their IDs when the format records them.
+	Decode(r io.Reader) (task.Tasks, error)
+	// Fields are the fields that survive encoding and decoding, which are all
+	// an import replaces
+	Fields() task.Fields
+}
+
+// Codecs holds every supported format by name
+var Codecs = map[string]Codec{
+	"todotxt":     TodoTxt{},
+	"md":          Markdown{},
+	"ics":         ICS{},
+	"taskwarrior": Taskwarrior{},
+	"org":         Org{},
+}
+
+const date = "2006-01-02"
+
+// walk calls f for every descendant of root, parents first and in order,
+// with the depth relative to root starting at 0
+func walk(tasks task.Tasks, root task.ID, f func(id task.ID, depth int) error) error {
+	var visit func(id task.ID, depth int) error
+	visit = func(id task.ID, depth int) error {
+		for _, c := range tasks.Children[id] {
+			if err := f(c, depth); err != nil {
+				return err
+			}
+			if err := visit(c, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return visit(root, 0)
+}
+
+// Subtree returns a view of tasks in which id is the only top level task,
+// so that encoding it includes id itself as well as its descendants
+func Subtree(tasks task.Tasks, id task.ID) task.Tasks {
+	if id == "root" {
+		return tasks
+	}
+	sub := task.Tasks{
+		Nodes:    tasks.Nodes,
+		Children: map[task.ID][]task.ID{},
+		Parent:   map[task.ID]task.ID{},
+	}
+	for k, v := range tasks.Children {
+		sub.Children[k] = v
+	}
+	for k, v := range tasks.Parent {
+		sub.Parent[k] = v
+	}
+	sub.Children["root"] = []task.ID{id}
+	sub.Parent[id] = "root"
+	return sub
+}
+
+// add puts t at the end of parent, generating an ID if it has none
+func add(tasks *task.Tasks, id task.ID, t task.Task, parent task.ID) (task.ID, error) {
+	if id == "" {
+		id = task.NewID()
+	}
+	tasks.Nodes[id] = t
+	return id, tasks.Move(id, parent, "", task.Below)
+}
@@ -0,0 +1,70 @@
+package format
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+func TestReimport(t *testing.T) {
+	day := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC)
+	original := task.Task{
+		Title:     "release",
+		Created:   created,
+		Due:       &day,
+		Start:     &day,
+		Status:    "review",
+		Priority:  "B",
+		Tags:      []string{"work"},
+		Notes:     []task.Note{{Created: created, Text: "ask for a review"}},
+		Reminders: []string{"1h before"},
+		Folded:    true,
+	}
+	for name, codec := range Codecs {
+		if name == "md" {
+			// tasks have no IDs to be matched by
+			continue
+		}
+		tasks := task.NewTasks()
+		tasks.Nodes["release"] = original
+		tasks.Children["root"] = []task.ID{"release"}
+		tasks.Parent["release"] = "root"
+
+		var buf bytes.Buffer
+		if err := codec.Encode(&buf, tasks, "root"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		imported, err := codec.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var id task.ID
+		for c, parent := range imported.Parent {
+			if parent == "root" && imported.Nodes[c].Title == "release" {
+				id = c
+			}
+		}
+		changed := imported.Nodes[id]
+		changed.Title = "release 1.0"
+		imported.Nodes[id] = changed
+
+		added, updated, err := tasks.Merge(imported, "root", codec.Fields())
+		if err != nil || added != 0 || updated != 1 {
+			t.Errorf("%s: added %d, updated %d, %v", name, added, updated, err)
+			continue
+		}
+		got := tasks.Nodes["release"]
+		if got.Title != "release 1.0" {
+			t.Errorf("%s: title not updated: %q", name, got.Title)
+		}
+		// what the format does not carry is left alone
+		kept := (task.AllFields &^ codec.Fields()).Overlay(got, original)
+		if !reflect.DeepEqual(got, kept) {
+			t.Errorf("%s: fields the format does not carry changed:\ngot  %+v\nwant %+v", name, got, kept)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package format
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+// ICS is the iCalendar format (RFC 5545), with every task written as a VTODO.
+//
+// The tree is kept with RELATED-TO;RELTYPE=PARENT, dependencies with
+// RELTYPE=DEPENDS-ON and notes as COMMENTs. The fold state and the order among
+// siblings are kept with X-TASKMAN-FOLDED and X-TASKMAN-ORDER. The UID is
+// the task ID, so importing the same calendar twice updates the tasks in place.
+type ICS struct{}
+
+// Todo is a single VTODO component
+type Todo struct {
+	ID     task.ID
+	Task   task.Task
+	Parent task.ID
+	// Order is the position among its siblings, or -1 when unknown
+	Order int
+}
+
+const (
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405Z"
+)
+
+func (ICS) Encode(w io.Writer, tasks task.Tasks, root task.ID) error {
+	todos := []Todo{}
+	err := walk(tasks, root, func(id task.ID, depth int) error {
+		todos = append(todos, TodoOf(tasks, id))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return WriteCalendar(w, todos)
+}
+
+func (ICS) Fields() task.Fields {
+	return task.FieldTitle | task.FieldCreated | task.FieldDone | task.FieldDue | task.FieldStart |
+		task.FieldPriority | task.FieldTags | task.FieldDepends | task.FieldNotes | task.FieldFolded
+}
+
+func (ICS) Decode(r io.Reader) (task.Tasks, error) {
+	todos, err := ReadCalendar(r)
+	if err != nil {
+		return task.NewTasks(), err
+	}
+
+	tasks := task.NewTasks()
+	byParent := map[task.ID][]Todo{}
+	for _, t := range todos {
+		tasks.Nodes[t.ID] = t.Task
+	}
+	for _, t := range todos {
+		parent := t.Parent
+		if _, found := tasks.Nodes[parent]; !found || parent == t.ID {
+			parent = "root"
+		}
+		byParent[parent] = append(byParent[parent], t)
+	}
+	for parent, children := range byParent {
+		sort.SliceStable(children, func(i, j int) bool {
+			return children[i].Order >= 0 && (children[j].Order < 0 || children[i].Order < children[j].Order)
+		})
+		for _, t := range children {
+			if err := tasks.Move(t.ID, parent, "", task.Below); err != nil {
+				return tasks, fmt.Errorf("%s: %w", t.ID, err)
+			}
+		}
+	}
+	return tasks, nil
+}
+
+// TodoOf returns the VTODO for a single task
+func TodoOf(tasks task.Tasks, id task.ID) Todo {
+	parent := tasks.Parent[id]
+	order := -1
+	for i, c := range tasks.Children[parent] {
+		if c == id {
+			order = i
+		}
+	}
+	return Todo{ID: id, Task: tasks.Nodes[id], Parent: parent, Order: order}
+}
+
+// WriteCalendar writes todos as a single VCALENDAR
+func WriteCalendar(w io.Writer, todos []Todo) error {
+	cw := &contentWriter{w: bufio.NewWriter(w)}
+	cw.line("BEGIN", "VCALENDAR")
+	cw.line("VERSION", "2.0")
+	cw.line("PRODID", "-//taskman//taskman//EN")
+	stamp := time.Now().UTC().Format(icsDateTime)
+	for _, todo := range todos {
+		t := todo.Task
+		cw.line("BEGIN", "VTODO")
+		cw.line("UID", escapeText(string(todo.ID)))
+		cw.line("DTSTAMP", stamp)
+		if !t.Created.IsZero() {
+			cw.line("CREATED", t.Created.UTC().Format(icsDateTime))
+		}
+		cw.line("SUMMARY", escapeText(t.Title))
+		if t.Start != nil {
+			cw.line("DTSTART;VALUE=DATE", t.Start.Format(icsDate))
+		}
+		if t.Due != nil {
+			cw.line("DUE;VALUE=DATE", t.Due.Format(icsDate))
+		}
+		if t.Done != nil {
+			cw.line("STATUS", "COMPLETED")
+			cw.line("COMPLETED", t.Done.UTC().Format(icsDateTime))
+		} else {
+			cw.line("STATUS", "NEEDS-ACTION")
+		}
+		if t.Priority != "" {
+			cw.line("PRIORITY", strconv.Itoa(min(int(t.Priority[0]-'A')+1, 9)))
+		}
+		if len(t.Tags) > 0 {
+			tags := make([]string, len(t.Tags))
+			for i, tag := range t.Tags {
+				tags[i] = escapeText(tag)
+			}
+			cw.line("CATEGORIES", strings.Join(tags, ","))
+		}
+		if todo.Parent != "" && todo.Parent != "root" {
+			cw.line("RELATED-TO;RELTYPE=PARENT", escapeText(string(todo.Parent)))
+		}
+		for _, dep := range t.Depends {
+			cw.line("RELATED-TO;RELTYPE=DEPENDS-ON", escapeText(string(dep)))
+		}
+		for _, note := range t.Notes {
+			cw.line("COMMENT;X-TASKMAN-CREATED="+note.Created.UTC().Format(icsDateTime), escapeText(note.Text))
+		}
+		if t.Folded {
+			cw.line("X-TASKMAN-FOLDED", "TRUE")
+		}
+		if todo.Order >= 0 {
+			cw.line("X-TASKMAN-ORDER", strconv.Itoa(todo.Order))
+		}
+		cw.line("END", "VTODO")
+	}
+	cw.line("END", "VCALENDAR")
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Flush()
+}
+
+// ReadCalendar reads every VTODO of one or more VCALENDARs. Other components
+// are skipped.
+func ReadCalendar(r io.Reader) ([]Todo, error) {
+	todos := []Todo{}
+	var (
+		current *Todo
+		// nested components such as VALARM, whose properties must not leak into the VTODO
+		nested int
+	)
+	lines, err := unfold(r)
+	if err != nil {
+		return todos, err
+	}
+	for n, line := range lines {
+		name, params, value, ok := parseContentLine(line)
+		if !ok {
+			return todos, fmt.Errorf("line %d: not a content line: %q", n+1, line)
+		}
+		switch {
+		case name == "BEGIN" && value == "VTODO":
+			current = &Todo{Order: -1, Task: task.Task{Created: time.Now()}}
+			continue
+		case name == "END" && value == "VTODO":
+			if current != nil && current.ID != "" {
+				todos = append(todos, *current)
+			}
+			current = nil
+			continue
+		case current == nil:
+			continue
+		case name == "BEGIN":
+			nested++
+		case name == "END":
+			nested--
+		}
+		if nested > 0 {
+			continue
+		}
+		if err := current.set(name, params, value); err != nil {
+			return todos, fmt.Errorf("line %d: %s: %w", n+1, name, err)
+		}
+	}
+	return todos, nil
+}
+
+func (todo *Todo) set(name string, params map[string]string, value string) error {
+	t := &todo.Task
+	switch name {
+	case "UID":
+		todo.ID = task.ID(unescapeText(value))
+	case "SUMMARY":
+		t.Title = unescapeText(value)
+	case "CREATED":
+		created, err := parseICSTime(value, params)
+		if err != nil {
+			return err
+		}
+		t.Created = created
+	case "DUE":
+		due, err := parseICSTime(value, params)
+		if err != nil {
+			return err
+		}
+		due = icsDay(due)
+		t.Due = &due
+	case "DTSTART":
+		start, err := parseICSTime(value, params)
+		if err != nil {
+			return err
+		}
+		start = icsDay(start)
+		t.Start = &start
+	case "COMPLETED":
+		done, err := parseICSTime(value, params)
+		if err != nil {
+			return err
+		}
+		t.Done = &done
+	case "STATUS":
+		if value == "COMPLETED" && t.Done == nil {
+			now := time.Now()
+			t.Done = &now
+		}
+	case "PRIORITY":
+		p, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if p > 0 && p <= 9 {
+			t.Priority = string(rune('A' + p - 1))
+		}
+	case "CATEGORIES":
+		for _, tag := range splitText(value) {
+			t.Tags = append(t.Tags, unescapeText(tag))
+		}
+	case "RELATED-TO":
+		switch params["RELTYPE"] {
+		case "", "PARENT":
+			todo.Parent = task.ID(unescapeText(value))
+		case "DEPENDS-ON":
+			t.Depends = append(t.Depends, task.ID(unescapeText(value)))
+		}
+	case "COMMENT":
+		note := task.Note{Text: unescapeText(value)}
+		if created, ok := params["X-TASKMAN-CREATED"]; ok {
+			note.Created, _ = time.Parse(icsDateTime, created)
+		}
+		t.Notes = append(t.Notes, note)
+	case "X-TASKMAN-FOLDED":
+		t.Folded = value == "TRUE"
+	case "X-TASKMAN-ORDER":
+		order, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		todo.Order = order
+	}
+	return nil
+}
+
+func parseICSTime(value string, params map[string]string) (time.Time, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(icsDate) {
+		return time.Parse(icsDate, value)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(icsDateTime, value)
+	}
+	loc := time.Local
+	if tzid, ok := params["TZID"]; ok {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	return time.ParseInLocation(strings.TrimSuffix(icsDateTime, "Z"), value, loc)
+}
+
+// icsDay returns the day t falls on where it was written, as midnight UTC like
+// every date of a task
+func icsDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// unfold joins lines that were split to fit into 75 octets
+func unfold(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseContentLine splits `NAME;PARAM=VALUE:value`
+func parseContentLine(line string) (name string, params map[string]string, value string, ok bool) {
+	params = map[string]string{}
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		}
+		if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", params, "", false
+	}
+	parts := strings.Split(line[:colon], ";")
+	for _, p := range parts[1:] {
+		if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], true
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' || s[i] == 'N' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// splitText splits a list value on the commas that are not escaped
+func splitText(s string) []string {
+	parts := []string{}
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// contentWriter writes content lines, folding them at 75 octets
+type contentWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (cw *contentWriter) line(name, value string) {
+	if cw.err != nil {
+		return
+	}
+	line := name + ":" + value
+	// continuation lines start with a space, which counts towards the limit
+	limit := 75
+	for len(line) > limit {
+		// do not split multi-byte characters
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if _, cw.err = cw.w.WriteString(line[:cut] + "\r\n "); cw.err != nil {
+			return
+		}
+		line = line[cut:]
+		limit = 74
+	}
+	_, cw.err = cw.w.WriteString(line + "\r\n")
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,84 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+func TestICS_RoundTrip(t *testing.T) {
+	due := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	tasks := task.NewTasks()
+	long := "a title that is long enough to be folded, with; characters that need escaping\nand a newline"
+	tasks.Nodes["parent"] = task.Task{Title: long, Folded: true, Tags: []string{"a,b", "c"}}
+	start := due.AddDate(0, 0, -7)
+	tasks.Nodes["first"] = task.Task{Title: "first", Start: &start, Due: &due, Priority: "B"}
+	tasks.Nodes["second"] = task.Task{Title: "second"}
+	tasks.Children["root"] = []task.ID{"parent"}
+	tasks.Children["parent"] = []task.ID{"second", "first"}
+	tasks.Parent = map[task.ID]task.ID{"parent": "root", "second": "parent", "first": "parent"}
+
+	var out bytes.Buffer
+	if err := (ICS{}).Encode(&out, tasks, "root"); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(out.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+
+	got, err := ICS{}.Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := got.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	parent := got.Nodes["parent"]
+	if parent.Title != long || !parent.Folded || strings.Join(parent.Tags, "|") != "a,b|c" {
+		t.Errorf("decoded %+v", parent)
+	}
+	if order := got.Children["parent"]; len(order) != 2 || order[0] != "second" {
+		t.Errorf("got children %v, want [second first]", order)
+	}
+	first := got.Nodes["first"]
+	if first.Due == nil || !first.Due.Equal(due) || first.Start == nil || !first.Start.Equal(start) || first.Priority != "B" {
+		t.Errorf("decoded %+v", first)
+	}
+}
+
+func TestICS_DecodeSkipsNested(t *testing.T) {
+	in := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:event\r\nEND:VEVENT\r\n" +
+		"BEGIN:VTODO\r\nUID:todo\r\nSUMMARY:outer\r\nBEGIN:VALARM\r\nSUMMARY:inner\r\nEND:VALARM\r\n" +
+		"DUE;TZID=Europe/London:20261020T170000\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	tasks, err := ICS{}.Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks.Children["root"]) != 1 || tasks.Nodes["todo"].Title != "outer" || tasks.Nodes["todo"].Due == nil {
+		t.Errorf("decoded %+v", tasks)
+	}
+}
+
+func TestICS_DecodeDays(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Tokyo"); err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	// midnight in Tokyo is the afternoon before in UTC
+	in := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:todo\r\nSUMMARY:todo\r\n" +
+		"DTSTART;TZID=Asia/Tokyo:20261013T000000\r\nDUE;TZID=Asia/Tokyo:20261020T000000\r\n" +
+		"END:VTODO\r\nEND:VCALENDAR\r\n"
+	tasks, err := ICS{}.Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	todo := tasks.Nodes["todo"]
+	due, start := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)
+	if todo.Due == nil || !todo.Due.Equal(due) || todo.Start == nil || !todo.Start.Equal(start) {
+		t.Errorf("got due %v, start %v, want %v, %v", todo.Due, todo.Start, due, start)
+	}
+}
@@ -0,0 +1,91 @@
+package format
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+// Markdown is a GitHub flavoured markdown task list, nested by indentation:
+//
+//   - [ ] title (due 2026-10-20)
+//   - [x] subtask
+//
+// Plain list items are read as tasks that are not done yet, and any other line
+// is ignored, so checklists can be pasted straight out of a PR description.
+type Markdown struct{}
+
+var (
+	listItem  = regexp.MustCompile(`^(\s*)[-*+]\s+(?:\[([ xX])\]\s+)?(.*)$`)
+	dueSuffix = regexp.MustCompile(`\s*\(due (\d{4}-\d{2}-\d{2})\)$`)
+)
+
+func (Markdown) Encode(w io.Writer, tasks task.Tasks, root task.ID) error {
+	return walk(tasks, root, func(id task.ID, depth int) error {
+		t := tasks.Nodes[id]
+		check := " "
+		if t.Done != nil {
+			check = "x"
+		}
+		line := strings.Repeat("  ", depth) + "- [" + check + "] " + t.Title
+		if t.Due != nil {
+			line += " (due " + t.Due.Format(date) + ")"
+		}
+		_, err := fmt.Fprintln(w, line)
+		return err
+	})
+}
+
+func (Markdown) Fields() task.Fields {
+	return task.FieldTitle | task.FieldDone | task.FieldDue
+}
+
+func (Markdown) Decode(r io.Reader) (task.Tasks, error) {
+	tasks := task.NewTasks()
+	now := time.Now()
+
+	type level struct {
+		indent int
+		id     task.ID
+	}
+	// the tasks above the current line that it could be nested under
+	stack := []level{{-1, "root"}}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := listItem.FindStringSubmatch(strings.ReplaceAll(scanner.Text(), "\t", "    "))
+		if m == nil {
+			continue
+		}
+		indent, title := len(m[1]), m[3]
+		t := task.Task{Created: now}
+		if m[2] == "x" || m[2] == "X" {
+			done := now
+			t.Done = &done
+		}
+		if due := dueSuffix.FindStringSubmatch(title); due != nil {
+			d, err := time.Parse(date, due[1])
+			if err != nil {
+				return tasks, err
+			}
+			t.Due = &d
+			title = title[:len(title)-len(due[0])]
+		}
+		t.Title = strings.TrimSpace(title)
+
+		for stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		id, err := add(&tasks, "", t, stack[len(stack)-1].id)
+		if err != nil {
+			return tasks, err
+		}
+		stack = append(stack, level{indent, id})
+	}
+	return tasks, scanner.Err()
+}
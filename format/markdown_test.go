@@ -0,0 +1,39 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMarkdown_RoundTrip(t *testing.T) {
+	in := `## Release checklist
+
+- [ ] Release v2 (due 2026-10-20)
+  - [x] write changelog
+    * fix typos
+  - [ ] tag release
+- [X] unrelated
+`
+	tasks, err := Markdown{}.Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tasks.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := (Markdown{}).Encode(&out, tasks, "root"); err != nil {
+		t.Fatal(err)
+	}
+	want := `- [ ] Release v2 (due 2026-10-20)
+  - [x] write changelog
+    - [ ] fix typos
+  - [ ] tag release
+- [x] unrelated
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,260 @@
+package format
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+// Org is an Emacs org-mode outline, with one heading per task nested by
+// level. Task IDs are kept in the property drawer, so the same list can be
+// edited in Emacs and imported again:
+//
+//	#+TITLE: tasks
+//	* TODO [#A] title :tag:
+//	  CLOSED: [2026-10-19 Mon 10:00] SCHEDULED: <2026-10-12 Mon> DEADLINE: <2026-10-20 Tue>
+//	  :PROPERTIES:
+//	  :ID:       abc
+//	  :CREATED:  [2026-10-01 Thu 09:00]
+//	  :VISIBILITY: folded
+//	  :END:
+//	  - Note taken on [2026-10-02 Fri 12:00] \\
+//	    a note
+type Org struct{}
+
+const (
+	orgDate     = "2006-01-02 Mon"
+	orgDateTime = "2006-01-02 Mon 15:04"
+)
+
+var (
+	orgHeading   = regexp.MustCompile(`^(\*+)\s+(.*)$`)
+	orgPriority  = regexp.MustCompile(`^\[#([A-Z])\]\s*`)
+	orgTags      = regexp.MustCompile(`\s+:([^\s]+):\s*$`)
+	orgProperty  = regexp.MustCompile(`^:([^:\s]+):\s*(.*)$`)
+	orgPlanning  = regexp.MustCompile(`(DEADLINE|CLOSED|SCHEDULED):\s*[<\[]([^>\]]*)[>\]]`)
+	orgTimestamp = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(?:\s+[^\s\d]+)?(?:\s+(\d{1,2}:\d{2}))?`)
+	orgNote      = regexp.MustCompile(`^- Note taken on \[([^\]]*)\]\s*(?:\\\\)?\s*$`)
+)
+
+func (Org) Encode(w io.Writer, tasks task.Tasks, root task.ID) error {
+	bw := bufio.NewWriter(w)
+	err := walk(tasks, root, func(id task.ID, depth int) error {
+		t := tasks.Nodes[id]
+		indent := strings.Repeat(" ", depth+2)
+
+		heading := strings.Repeat("*", depth+1) + " TODO "
+		if t.Done != nil {
+			heading = strings.Repeat("*", depth+1) + " DONE "
+		}
+		if t.Priority != "" {
+			heading += "[#" + t.Priority + "] "
+		}
+		heading += t.Title
+		if len(t.Tags) > 0 {
+			heading += " :" + strings.Join(t.Tags, ":") + ":"
+		}
+		fmt.Fprintln(bw, heading)
+
+		planning := []string{}
+		if t.Done != nil {
+			planning = append(planning, "CLOSED: ["+t.Done.Local().Format(orgDateTime)+"]")
+		}
+		if t.Start != nil {
+			planning = append(planning, "SCHEDULED: <"+t.Start.Format(orgDate)+">")
+		}
+		if t.Due != nil {
+			planning = append(planning, "DEADLINE: <"+t.Due.Format(orgDate)+">")
+		}
+		if len(planning) > 0 {
+			fmt.Fprintln(bw, indent+strings.Join(planning, " "))
+		}
+
+		fmt.Fprintln(bw, indent+":PROPERTIES:")
+		fmt.Fprintln(bw, indent+":ID:       "+string(id))
+		if !t.Created.IsZero() {
+			fmt.Fprintln(bw, indent+":CREATED:  ["+t.Created.Local().Format(orgDateTime)+"]")
+		}
+		if t.Folded {
+			fmt.Fprintln(bw, indent+":VISIBILITY: folded")
+		}
+		fmt.Fprintln(bw, indent+":END:")
+
+		for _, note := range t.Notes {
+			fmt.Fprintln(bw, indent+"- Note taken on ["+note.Created.Local().Format(orgDateTime)+"] \\\\")
+			for _, line := range strings.Split(note.Text, "\n") {
+				fmt.Fprintln(bw, indent+"  "+line)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (Org) Fields() task.Fields {
+	return task.FieldTitle | task.FieldCreated | task.FieldDone | task.FieldDue | task.FieldStart |
+		task.FieldPriority | task.FieldTags | task.FieldNotes | task.FieldFolded
+}
+
+func (Org) Decode(r io.Reader) (task.Tasks, error) {
+	tasks := task.NewTasks()
+	type level struct {
+		depth int
+		id    task.ID
+	}
+	stack := []level{{0, "root"}}
+
+	var (
+		current    *task.Task
+		currentID  task.ID
+		depth      int
+		parent     task.ID
+		inDrawer   bool
+		noteOpened bool
+	)
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		if currentID == "" {
+			currentID = task.NewID()
+		}
+		if _, err := add(&tasks, currentID, *current, parent); err != nil {
+			return fmt.Errorf("%s: %w", currentID, err)
+		}
+		stack = append(stack, level{depth, currentID})
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if m := orgHeading.FindStringSubmatch(line); m != nil {
+			if err := flush(); err != nil {
+				return tasks, err
+			}
+			depth = len(m[1])
+			// levels may be skipped, so the parent is the closest heading above with fewer stars
+			for len(stack) > 1 && stack[len(stack)-1].depth >= depth {
+				stack = stack[:len(stack)-1]
+			}
+			parent = stack[len(stack)-1].id
+			t := parseOrgHeading(m[2])
+			current, currentID, inDrawer, noteOpened = &t, "", false, false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == ":PROPERTIES:":
+			inDrawer = true
+		case inDrawer && trimmed == ":END:":
+			inDrawer = false
+		case inDrawer:
+			m := orgProperty.FindStringSubmatch(trimmed)
+			if m == nil {
+				continue
+			}
+			switch strings.ToUpper(m[1]) {
+			case "ID":
+				currentID = task.ID(strings.TrimSpace(m[2]))
+			case "CREATED":
+				if created, ok := parseOrgTimestamp(strings.Trim(m[2], "[]<>")); ok {
+					current.Created = created
+				}
+			case "VISIBILITY":
+				current.Folded = strings.TrimSpace(m[2]) == "folded"
+			}
+		case orgPlanning.MatchString(trimmed) && len(current.Notes) == 0:
+			for _, m := range orgPlanning.FindAllStringSubmatch(trimmed, -1) {
+				ts, ok := parseOrgTimestamp(m[2])
+				if !ok {
+					return tasks, fmt.Errorf("line %d: bad timestamp %q", n, m[2])
+				}
+				switch m[1] {
+				case "DEADLINE":
+					due := time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC)
+					current.Due = &due
+				case "SCHEDULED":
+					start := time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC)
+					current.Start = &start
+				case "CLOSED":
+					current.Done = &ts
+				}
+			}
+		case orgNote.MatchString(trimmed):
+			m := orgNote.FindStringSubmatch(trimmed)
+			created, _ := parseOrgTimestamp(m[1])
+			current.Notes = append(current.Notes, task.Note{Created: created})
+			noteOpened = true
+		case trimmed != "":
+			// any other text is kept as a note, continuing the last one
+			if !noteOpened {
+				current.Notes = append(current.Notes, task.Note{Created: current.Created})
+				noteOpened = true
+			}
+			note := &current.Notes[len(current.Notes)-1]
+			if note.Text != "" {
+				note.Text += "\n"
+			}
+			note.Text += trimmed
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return tasks, err
+	}
+	return tasks, flush()
+}
+
+// parseOrgHeading reads the keyword, priority, title and tags of a heading
+func parseOrgHeading(s string) task.Task {
+	t := task.Task{Created: time.Now()}
+	switch {
+	case strings.HasPrefix(s, "DONE "):
+		done := time.Now()
+		t.Done = &done
+		s = s[len("DONE "):]
+	case strings.HasPrefix(s, "TODO "):
+		s = s[len("TODO "):]
+	}
+	if m := orgPriority.FindStringSubmatch(s); m != nil {
+		t.Priority = m[1]
+		s = s[len(m[0]):]
+	}
+	if m := orgTags.FindStringSubmatchIndex(s); m != nil {
+		for _, tag := range strings.Split(s[m[2]:m[3]], ":") {
+			if tag != "" {
+				t.Tags = append(t.Tags, tag)
+			}
+		}
+		s = s[:m[0]]
+	}
+	t.Title = strings.TrimSpace(s)
+	return t
+}
+
+// parseOrgTimestamp reads the inside of an active or inactive timestamp,
+// such as 2026-10-19 Mon 10:00, in local time
+func parseOrgTimestamp(s string) (time.Time, bool) {
+	m := orgTimestamp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return time.Time{}, false
+	}
+	layout, value := "2006-01-02", m[1]
+	if m[2] != "" {
+		layout, value = "2006-01-02 15:04", m[1]+" "+m[2]
+	}
+	t, err := time.ParseInLocation(layout, value, time.Local)
+	return t, err == nil
+}
@@ -0,0 +1,72 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestOrg_Decode(t *testing.T) {
+	in := `#+TITLE: garden
+
+* TODO [#B] plant tomatoes :outside:
+  SCHEDULED: <2026-05-01 Fri> DEADLINE: <2026-05-10 Sun>
+  :PROPERTIES:
+  :ID:       plant
+  :VISIBILITY: folded
+  :END:
+  - Note taken on [2026-01-03 Sat 10:00] \\
+    buy seeds first
+*** DONE dig beds
+    CLOSED: [2026-01-04 Sun 10:00]
+** TODO water
+* weed
+`
+	tasks, err := Org{}.Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tasks.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := (Markdown{}).Encode(&out, tasks, "root"); err != nil {
+		t.Fatal(err)
+	}
+	want := `- [ ] plant tomatoes (due 2026-05-10)
+  - [x] dig beds
+  - [ ] water
+- [ ] weed
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	plant := tasks.Nodes["plant"]
+	if plant.Priority != "B" || !plant.Folded || plant.Start == nil || plant.Start.Day() != 1 || len(plant.Tags) != 1 || len(plant.Notes) != 1 || plant.Notes[0].Text != "buy seeds first" {
+		t.Errorf("decoded %+v", plant)
+	}
+}
+
+func TestOrg_RoundTrip(t *testing.T) {
+	tasks, err := TodoTxt{}.Decode(strings.NewReader("x 2026-10-19 Release due:2026-10-20 id:r\n  changelog id:c\n  tag id:t\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first bytes.Buffer
+	if err := (Org{}).Encode(&first, tasks, "root"); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Org{}.Decode(strings.NewReader(first.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var second bytes.Buffer
+	if err := (Org{}).Encode(&second, again, "root"); err != nil {
+		t.Fatal(err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("changed after a round trip:\n%s\nthen:\n%s", first.String(), second.String())
+	}
+}
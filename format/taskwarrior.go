@@ -0,0 +1,279 @@
+package format
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+// Taskwarrior is the JSON format of `task export` and `task import`.
+//
+// Taskwarrior has no subtasks, so the tree is written as dotted projects made
+// of the titles of each task's ancestors, and read back by nesting tasks under
+// the tasks named by their project. Task IDs are kept in a taskman_id UDA, and
+// used to derive a stable uuid for tasks that were not created by Taskwarrior.
+type Taskwarrior struct{}
+
+type twTask struct {
+	UUID        string         `json:"uuid"`
+	Description string         `json:"description"`
+	Status      string         `json:"status"`
+	Entry       twTime         `json:"entry"`
+	End         *twTime        `json:"end,omitempty"`
+	Due         *twTime        `json:"due,omitempty"`
+	Project     string         `json:"project,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Priority    string         `json:"priority,omitempty"`
+	Depends     twDepends      `json:"depends,omitempty"`
+	Annotations []twAnnotation `json:"annotations,omitempty"`
+	TaskmanID   task.ID        `json:"taskman_id,omitempty"`
+}
+
+type twAnnotation struct {
+	Entry       twTime `json:"entry"`
+	Description string `json:"description"`
+}
+
+const twTimeFormat = "20060102T150405Z"
+
+// twTime is a timestamp in the compact ISO 8601 form Taskwarrior uses
+type twTime struct{ time.Time }
+
+func (t twTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.UTC().Format(twTimeFormat))
+}
+
+func (t *twTime) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.Parse(twTimeFormat, s)
+	if err != nil {
+		// older versions and hand edited files
+		parsed, err = time.Parse(time.RFC3339, s)
+	}
+	t.Time = parsed
+	return err
+}
+
+// twDepends is a list of uuids, written as an array by Taskwarrior 2.6 and
+// later, and as a comma separated string before that
+type twDepends []string
+
+func (d *twDepends) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*d = list
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*d = nil
+	for _, uuid := range strings.Split(s, ",") {
+		if uuid = strings.TrimSpace(uuid); uuid != "" {
+			*d = append(*d, uuid)
+		}
+	}
+	return nil
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// uuidOf returns the ID itself if it is a uuid already, or a name based uuid
+// (version 5) derived from it, so that exporting twice gives the same uuids
+func uuidOf(id task.ID) string {
+	if uuidPattern.MatchString(string(id)) {
+		return string(id)
+	}
+	// the URL namespace
+	namespace := []byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	sum := sha1.Sum(append(namespace, "taskman:"+id...))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+var (
+	toTWPriority   = map[string]string{"A": "H", "B": "M"}
+	fromTWPriority = map[string]string{"H": "A", "M": "B", "L": "C"}
+)
+
+func (Taskwarrior) Encode(w io.Writer, tasks task.Tasks, root task.ID) error {
+	records := []twTask{}
+	err := walk(tasks, root, func(id task.ID, depth int) error {
+		t := tasks.Nodes[id]
+		r := twTask{
+			UUID:        uuidOf(id),
+			Description: t.Title,
+			Status:      "pending",
+			Entry:       twTime{t.Created},
+			Project:     twProject(tasks, id, root),
+			Tags:        t.Tags,
+			TaskmanID:   id,
+		}
+		if t.Created.IsZero() {
+			r.Entry = twTime{time.Now()}
+		}
+		if t.Done != nil {
+			r.Status = "completed"
+			r.End = &twTime{*t.Done}
+		}
+		if t.Due != nil {
+			// due dates are days, which Taskwarrior keeps as local midnight
+			due := time.Date(t.Due.Year(), t.Due.Month(), t.Due.Day(), 0, 0, 0, 0, time.Local)
+			r.Due = &twTime{due}
+		}
+		if t.Priority != "" {
+			r.Priority = "L"
+			if p, ok := toTWPriority[t.Priority]; ok {
+				r.Priority = p
+			}
+		}
+		for _, dep := range t.Depends {
+			r.Depends = append(r.Depends, uuidOf(dep))
+		}
+		for _, note := range t.Notes {
+			r.Annotations = append(r.Annotations, twAnnotation{twTime{note.Created}, note.Text})
+		}
+		records = append(records, r)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+// twProject joins the titles of the ancestors of id below root with dots
+func twProject(tasks task.Tasks, id, root task.ID) string {
+	names := []string{}
+	for parent := tasks.Parent[id]; parent != root && parent != "root" && parent != ""; parent = tasks.Parent[parent] {
+		names = append([]string{twProjectName(tasks.Nodes[parent].Title)}, names...)
+	}
+	return strings.Join(names, ".")
+}
+
+func twProjectName(title string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(title), "-"), ".", "_")
+}
+
+func (Taskwarrior) Fields() task.Fields {
+	return task.FieldTitle | task.FieldCreated | task.FieldDone | task.FieldDue |
+		task.FieldPriority | task.FieldTags | task.FieldDepends | task.FieldNotes
+}
+
+func (Taskwarrior) Decode(r io.Reader) (task.Tasks, error) {
+	tasks := task.NewTasks()
+	var records []twTask
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return tasks, err
+	}
+
+	ids := map[string]task.ID{}
+	kept := []twTask{}
+	for _, r := range records {
+		// deleted tasks are gone, and recurring ones are templates for the pending instances
+		if r.Status == "deleted" || r.Status == "recurring" {
+			continue
+		}
+		id := r.TaskmanID
+		if id == "" {
+			id = task.ID(r.UUID)
+		}
+		ids[r.UUID] = id
+		kept = append(kept, r)
+	}
+	// projects before the tasks in them, so that tasks named like a project are used as one
+	sort.SliceStable(kept, func(i, j int) bool {
+		a, b := projectDepth(kept[i].Project), projectDepth(kept[j].Project)
+		if a != b {
+			return a < b
+		}
+		return kept[i].Entry.Before(kept[j].Entry.Time)
+	})
+
+	for _, r := range kept {
+		t := task.Task{
+			Title:    r.Description,
+			Created:  r.Entry.Time,
+			Tags:     r.Tags,
+			Priority: fromTWPriority[r.Priority],
+		}
+		if r.Status == "completed" {
+			end := r.Entry.Time
+			if r.End != nil {
+				end = r.End.Time
+			}
+			t.Done = &end
+		}
+		if r.Due != nil {
+			local := r.Due.Local()
+			due := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+			t.Due = &due
+		}
+		for _, uuid := range r.Depends {
+			if dep, ok := ids[uuid]; ok {
+				t.Depends = append(t.Depends, dep)
+			}
+		}
+		for _, a := range r.Annotations {
+			t.Notes = append(t.Notes, task.Note{Created: a.Entry.Time, Text: a.Description})
+		}
+
+		parent, err := projectTask(&tasks, r.Project)
+		if err != nil {
+			return tasks, err
+		}
+		if _, err := add(&tasks, ids[r.UUID], t, parent); err != nil {
+			return tasks, fmt.Errorf("%s: %w", r.UUID, err)
+		}
+	}
+	return tasks, nil
+}
+
+func projectDepth(project string) int {
+	if project == "" {
+		return 0
+	}
+	return strings.Count(project, ".") + 1
+}
+
+// projectTask returns the task a dotted project refers to, creating the parts
+// of it that do not exist yet. Created tasks get IDs derived from the project,
+// so that importing again does not create them twice.
+func projectTask(tasks *task.Tasks, project string) (task.ID, error) {
+	parent := task.ID("root")
+	if project == "" {
+		return parent, nil
+	}
+	for i, name := range strings.Split(project, ".") {
+		found := false
+		for _, c := range tasks.Children[parent] {
+			if twProjectName(tasks.Nodes[c].Title) == name {
+				parent, found = c, true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		id := task.ID("project:" + strings.Join(strings.Split(project, ".")[:i+1], "."))
+		var err error
+		if parent, err = add(tasks, id, task.Task{Title: name, Created: time.Now()}, parent); err != nil {
+			return parent, err
+		}
+	}
+	return parent, nil
+}
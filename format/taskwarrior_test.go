@@ -0,0 +1,62 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTaskwarrior_Decode(t *testing.T) {
+	in := `[
+{"uuid":"00000000-0000-0000-0000-000000000002","description":"plant tomatoes","status":"pending","entry":"20260102T100000Z",
+ "project":"home.garden","tags":["outside"],"priority":"M","due":"20260510T120000Z",
+ "depends":"00000000-0000-0000-0000-000000000001",
+ "annotations":[{"entry":"20260103T100000Z","description":"buy seeds first"}]},
+{"uuid":"00000000-0000-0000-0000-000000000001","description":"dig beds","status":"completed","entry":"20260101T100000Z","end":"20260104T100000Z","project":"home.garden"},
+{"uuid":"00000000-0000-0000-0000-000000000003","description":"home","status":"pending","entry":"20260101T090000Z"},
+{"uuid":"00000000-0000-0000-0000-000000000004","description":"gone","status":"deleted","entry":"20260101T090000Z"}
+]`
+	tasks, err := Taskwarrior{}.Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tasks.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := (Markdown{}).Encode(&out, tasks, "root"); err != nil {
+		t.Fatal(err)
+	}
+	want := `- [ ] home
+  - [ ] garden
+    - [x] dig beds
+    - [ ] plant tomatoes (due 2026-05-10)
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	plant := tasks.Nodes["00000000-0000-0000-0000-000000000002"]
+	if plant.Priority != "B" || len(plant.Notes) != 1 || len(plant.Depends) != 1 || plant.Depends[0] != "00000000-0000-0000-0000-000000000001" {
+		t.Errorf("decoded %+v", plant)
+	}
+}
+
+func TestTaskwarrior_RoundTrip(t *testing.T) {
+	tasks, err := TodoTxt{}.Decode(strings.NewReader("Release id:r\n  changelog id:c\n  tag id:t\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tw bytes.Buffer
+	if err := (Taskwarrior{}).Encode(&tw, tasks, "root"); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Taskwarrior{}.Decode(&tw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Nodes) != len(tasks.Nodes) || again.Parent["c"] != "r" || again.Parent["t"] != "r" {
+		t.Errorf("tree changed: %v", again.Parent)
+	}
+}
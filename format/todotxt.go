@@ -0,0 +1,223 @@
+package format
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+// TodoTxt is the todo.txt format (https://github.com/todotxt/todo.txt).
+//
+// The top level task above each task becomes its +project and tags become
+// @contexts. The tree is kept with the id: and parent: extensions, and lines
+// without a parent: key are nested under the closest less indented line above,
+// or under their first +project. Any other +projects on a line are kept as tags.
+type TodoTxt struct{}
+
+var priority = regexp.MustCompile(`^\(([A-Z])\)$`)
+
+func (TodoTxt) Encode(w io.Writer, tasks task.Tasks, root task.ID) error {
+	return walk(tasks, root, func(id task.ID, depth int) error {
+		t := tasks.Nodes[id]
+		parts := []string{}
+		if t.Done != nil {
+			parts = append(parts, "x", t.Done.Format(date))
+		} else if t.Priority != "" {
+			parts = append(parts, "("+t.Priority+")")
+		}
+		if !t.Created.IsZero() {
+			parts = append(parts, t.Created.Format(date))
+		}
+		if t.Title != "" {
+			parts = append(parts, t.Title)
+		}
+		if project := projectOf(tasks, id, root); project != "" {
+			parts = append(parts, "+"+project)
+		}
+		for _, tag := range t.Tags {
+			parts = append(parts, "@"+tag)
+		}
+		if t.Due != nil {
+			parts = append(parts, "due:"+t.Due.Format(date))
+		}
+		if t.Done != nil && t.Priority != "" {
+			parts = append(parts, "pri:"+t.Priority)
+		}
+		parts = append(parts, "id:"+string(id))
+		if parent := tasks.Parent[id]; parent != root {
+			parts = append(parts, "parent:"+string(parent))
+		}
+		_, err := fmt.Fprintln(w, strings.Join(parts, " "))
+		return err
+	})
+}
+
+// projectOf returns the title of the top level task above id, as a single word
+func projectOf(tasks task.Tasks, id, root task.ID) string {
+	parent := tasks.Parent[id]
+	if id == root || parent == root {
+		return ""
+	}
+	for tasks.Parent[parent] != root {
+		parent = tasks.Parent[parent]
+	}
+	return strings.Join(strings.Fields(tasks.Nodes[parent].Title), "-")
+}
+
+type todoLine struct {
+	id      task.ID
+	task    task.Task
+	parent  task.ID
+	project string
+	indent  int
+}
+
+func (TodoTxt) Fields() task.Fields {
+	return task.FieldTitle | task.FieldCreated | task.FieldDone | task.FieldDue | task.FieldPriority | task.FieldTags
+}
+
+func (TodoTxt) Decode(r io.Reader) (task.Tasks, error) {
+	tasks := task.NewTasks()
+	lines := []todoLine{}
+	byID := map[task.ID]bool{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		line, err := parseTodoLine(text)
+		if err != nil {
+			return tasks, fmt.Errorf("line %d: %w", n, err)
+		}
+		if line.id == "" {
+			line.id = task.NewID()
+		}
+		byID[line.id] = true
+		tasks.Nodes[line.id] = line.task
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return tasks, err
+	}
+
+	// a project is a top level line going by its name, as written by Encode
+	projects := map[string]task.ID{}
+	for i, line := range lines {
+		indented := line.parent == "" && indentParent(lines, i) != ""
+		if !byID[line.parent] && !indented && line.project == "" {
+			name := strings.Join(strings.Fields(line.task.Title), "-")
+			if _, ok := projects[name]; !ok {
+				projects[name] = line.id
+			}
+		}
+	}
+	for i, line := range lines {
+		parent := task.ID("root")
+		switch {
+		case byID[line.parent]:
+			parent = line.parent
+		case line.parent == "" && indentParent(lines, i) != "":
+			parent = indentParent(lines, i)
+		case line.project != "":
+			// or one made for it, with an ID derived from the name so that
+			// importing again does not make it twice
+			if _, ok := projects[line.project]; !ok {
+				id, err := add(&tasks, task.ID("project:"+line.project), task.Task{Title: line.project, Created: time.Now()}, "root")
+				if err != nil {
+					return tasks, err
+				}
+				projects[line.project] = id
+			}
+			parent = projects[line.project]
+		}
+		if err := tasks.Move(line.id, parent, "", task.Below); err != nil {
+			return tasks, fmt.Errorf("%s: %w", line.id, err)
+		}
+	}
+	return tasks, nil
+}
+
+// indentParent returns the closest line above i that is indented less than it
+func indentParent(lines []todoLine, i int) task.ID {
+	for j := i - 1; j >= 0; j-- {
+		if lines[j].indent < lines[i].indent {
+			return lines[j].id
+		}
+	}
+	return ""
+}
+
+func parseTodoLine(text string) (todoLine, error) {
+	line := todoLine{indent: len(text) - len(strings.TrimLeft(text, " \t"))}
+	fields := strings.Fields(text)
+
+	// an optional date at the start of what is left
+	nextDate := func() *time.Time {
+		if len(fields) == 0 {
+			return nil
+		}
+		d, err := time.Parse(date, fields[0])
+		if err != nil {
+			return nil
+		}
+		fields = fields[1:]
+		return &d
+	}
+
+	if fields[0] == "x" {
+		fields = fields[1:]
+		done := nextDate()
+		if done == nil {
+			// completion dates are optional, but done tasks need one
+			now := time.Now().Truncate(time.Hour * 24)
+			done = &now
+		}
+		line.task.Done = done
+	} else if m := priority.FindStringSubmatch(fields[0]); m != nil {
+		line.task.Priority = m[1]
+		fields = fields[1:]
+	}
+	line.task.Created = time.Now()
+	if created := nextDate(); created != nil {
+		line.task.Created = *created
+	}
+
+	title := []string{}
+	for _, f := range fields {
+		key, value := "", ""
+		if i := strings.Index(f, ":"); i > 0 {
+			key, value = f[:i], f[i+1:]
+		}
+		switch {
+		case len(f) > 1 && f[0] == '+' && line.project == "":
+			line.project = f[1:]
+		case len(f) > 1 && f[0] == '+':
+			line.task.Tags = append(line.task.Tags, f[1:])
+		case len(f) > 1 && f[0] == '@':
+			line.task.Tags = append(line.task.Tags, f[1:])
+		case key == "due":
+			due, err := time.Parse(date, value)
+			if err != nil {
+				return line, fmt.Errorf("due: %w", err)
+			}
+			line.task.Due = &due
+		case key == "pri" && len(value) == 1:
+			line.task.Priority = value
+		case key == "id":
+			line.id = task.ID(value)
+		case key == "parent":
+			line.parent = task.ID(value)
+		default:
+			title = append(title, f)
+		}
+	}
+	line.task.Title = strings.Join(title, " ")
+	return line, nil
+}
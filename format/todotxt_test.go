@@ -0,0 +1,84 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTodoTxt_RoundTrip(t *testing.T) {
+	in := `(A) 2026-10-01 Call mom +Family @phone due:2026-10-20 id:call parent:family
+x 2026-10-18 2026-10-02 Buy milk +Family @shop pri:B id:milk parent:family
+2026-10-01 Family id:family
+`
+	tasks, err := TodoTxt{}.Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tasks.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	call := tasks.Nodes["call"]
+	if call.Title != "Call mom" || call.Priority != "A" || call.Due == nil || len(call.Tags) != 1 {
+		t.Errorf("decoded %+v", call)
+	}
+	if tasks.Nodes["milk"].Done == nil || tasks.Parent["milk"] != "family" {
+		t.Errorf("decoded %+v under %s", tasks.Nodes["milk"], tasks.Parent["milk"])
+	}
+
+	var out bytes.Buffer
+	if err := (TodoTxt{}).Encode(&out, tasks, "root"); err != nil {
+		t.Fatal(err)
+	}
+	want := `2026-10-01 Family id:family
+(A) 2026-10-01 Call mom +Family @phone due:2026-10-20 id:call parent:family
+x 2026-10-18 2026-10-02 Buy milk +Family @shop pri:B id:milk parent:family
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestTodoTxt_Indentation(t *testing.T) {
+	in := "Release\n  changelog\n    typos\n  tag\nOther\n"
+	tasks, err := TodoTxt{}.Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := tasks.Children["root"][0]
+	titles := []string{}
+	for _, c := range tasks.Children[release] {
+		titles = append(titles, tasks.Nodes[c].Title)
+	}
+	if got := strings.Join(titles, ","); got != "changelog,tag" {
+		t.Errorf("got children %s", got)
+	}
+	if len(tasks.Children["root"]) != 2 {
+		t.Errorf("got %d top level tasks, want 2", len(tasks.Children["root"]))
+	}
+}
+
+func TestTodoTxt_Projects(t *testing.T) {
+	in := "Call mom +Family\nBuy milk +Shopping +Family\n"
+	tasks, err := TodoTxt{}.Decode(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the same project gets the same ID every time, for Merge to match
+	family, shopping := tasks.Children["project:Family"], tasks.Children["project:Shopping"]
+	if len(family) != 1 || len(shopping) != 1 {
+		t.Fatalf("got projects %v", tasks.Children["root"])
+	}
+	if milk := tasks.Nodes[shopping[0]]; len(milk.Tags) != 1 || milk.Tags[0] != "Family" {
+		t.Errorf("lost the other project: %+v", milk)
+	}
+
+	// a top level line going by the name of the project is the project
+	tasks, err = TodoTxt{}.Decode(strings.NewReader("Call mom +Family\nFamily\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root := tasks.Children["root"]; len(root) != 1 || tasks.Nodes[root[0]].Title != "Family" || len(tasks.Children[root[0]]) != 1 {
+		t.Errorf("got %d top level tasks, want only Family", len(root))
+	}
+}
@@ -3,7 +3,9 @@ module github.com/td0m/taskman
 go 1.16
 
 require (
+	github.com/atotto/clipboard v0.1.2
 	github.com/charmbracelet/bubbles v0.7.6
 	github.com/charmbracelet/bubbletea v0.13.2
 	github.com/charmbracelet/lipgloss v0.1.2
+	github.com/mattn/go-runewidth v0.0.10
 )
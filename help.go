@@ -0,0 +1,175 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/td0m/taskman/ui"
+)
+
+// binding documents keys handled outside of the actions, by a mode or a view
+// of its own
+type binding struct {
+	keys []string
+	name string
+	help string
+}
+
+// viewBinding is a binding of a view, which also does what its keys do there
+type viewBinding struct {
+	binding
+	// run is given the key pressed
+	run func(m *app, key string) error
+}
+
+// modeKeys documents the keys of each mode but the normal one, whose keys are
+// the actions
+var modeKeys = []struct {
+	name  string
+	modes []mode
+	keys  []binding
+}{
+	{"title", []mode{titleMode}, []binding{
+		{[]string{"enter"}, "save", "save the title"},
+		{[]string{"tab", "shift+tab"}, "indent", "indent or outdent the task"},
+		{[]string{"esc"}, "cancel", "leave the title as it was"},
+	}},
+	{"date", []mode{dateMode, startMode}, []binding{
+		{[]string{"enter"}, "set", "set the date, or clear it if left empty"},
+		{nil, "dates", "today, fri, next fri, in 2 weeks, 21 feb"},
+		{[]string{"esc"}, "cancel", "leave the date as it was"},
+	}},
+	{"reminders", []mode{remindMode}, []binding{
+		{[]string{"enter"}, "set", "set the comma separated reminders"},
+		{nil, "reminders", "1h before, day before, morning of, at 14:30"},
+		{[]string{"esc"}, "cancel", "leave the reminders as they were"},
+	}},
+	{"palette", []mode{paletteMode}, []binding{
+		{[]string{"enter"}, "run", "run the selected action, with what follows its name"},
+		{[]string{"tab"}, "complete", "complete the name of the selected action"},
+		{[]string{"up", "down"}, "select", "select another action"},
+		{[]string{"esc"}, "close", "close the palette"},
+	}},
+	{"help", []mode{helpMode}, []binding{
+		{[]string{"j", "k"}, "scroll", "scroll down or up"},
+		{[]string{"esc", "?"}, "close", "close the help"},
+	}},
+}
+
+// viewKeys are the keys that differ in each view
+var viewKeys = map[view][]viewBinding{
+	agendaView:   agendaKeys,
+	calendarView: calendarKeys,
+	boardView:    boardKeys,
+	timelineView: timelineKeys,
+	burndownView: burndownKeys,
+	reportView:   reportKeys,
+}
+
+// arrows stand in for the keys they point like
+var arrows = map[string]string{"left": "h", "down": "j", "up": "k", "right": "l"}
+
+// viewKey runs the binding of the current view for a key, returning false
+// for the keys left to the actions
+func (m *app) viewKey(key string) (bool, error) {
+	pressed := key
+	if k, ok := arrows[key]; ok {
+		pressed = k
+	}
+	for _, b := range viewKeys[m.view] {
+		for _, k := range b.keys {
+			if k == pressed {
+				return true, b.run(m, pressed)
+			}
+		}
+	}
+	// the charts have no tasks for the other actions to act on
+	return m.view.chart() && !anywhere(key), nil
+}
+
+// normalHints are the actions the footer hints at in the normal mode
+var normalHints = []string{"help", "palette", "new", "edit", "toggle", "due", "view"}
+
+var hintKey = lipgloss.NewStyle().Foreground(ui.Primary)
+
+// helpKey handles the keys of the help, which scroll it or close it
+func (m *app) helpKey(key string) {
+	switch key {
+	case "j", "down":
+		m.viewport.LineDown(1)
+	case "k", "up":
+		m.viewport.LineUp(1)
+	case "?", "q":
+		m.mode = normalMode
+		m.setCursor(m.cursor)
+	}
+}
+
+// renderHelp lists every key, those of the current view first
+func (m app) renderHelp() string {
+	var b strings.Builder
+	line := func(keys []string, name, help string) {
+		k := runewidth.FillRight("  "+strings.Join(keys, " "), 16)
+		b.WriteString(hintKey.Render(k) + runewidth.FillRight(name, 12) + paletteItem.Render(help) + "\n")
+	}
+	if keys := viewKeys[m.view]; len(keys) > 0 {
+		b.WriteString(ui.RenderSection(viewNames[m.view]+" view") + "\n")
+		for _, k := range keys {
+			line(k.keys, k.name, k.help)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(ui.RenderSection("normal") + "\n")
+	for _, a := range actions {
+		line(a.keys, a.name, a.help)
+	}
+	for _, group := range modeKeys {
+		b.WriteString("\n" + ui.RenderSection(group.name) + "\n")
+		for _, k := range group.keys {
+			line(k.keys, k.name, k.help)
+		}
+	}
+	return b.String()
+}
+
+// hints are the keys worth knowing in the current mode
+func (m app) hints() []binding {
+	for _, group := range modeKeys {
+		for _, mode := range group.modes {
+			if mode == m.mode {
+				return group.keys
+			}
+		}
+	}
+	hints := []binding{}
+	for _, k := range viewKeys[m.view] {
+		hints = append(hints, k.binding)
+	}
+	for _, name := range normalHints {
+		for _, a := range actions {
+			if a.name == name && (!m.view.chart() || anywhere(a.keys[0])) {
+				hints = append(hints, binding{a.keys[:1], a.name, a.help})
+			}
+		}
+	}
+	return hints
+}
+
+// renderHints renders as many hints as fit on a line
+func (m app) renderHints() string {
+	s, width := "", 0
+	for _, h := range m.hints() {
+		if len(h.keys) == 0 {
+			continue
+		}
+		keys := strings.Join(h.keys, "/")
+		w := runewidth.StringWidth(keys+" "+h.name) + 2
+		if width+w > m.viewport.Width {
+			break
+		}
+		s += " " + hintKey.Render(keys) + " " + paletteItem.Render(h.name) + " "
+		width += w
+	}
+	return s
+}
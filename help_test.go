@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+func TestKeymap(t *testing.T) {
+	seen := map[string]string{}
+	for _, a := range actions {
+		for _, key := range a.keys {
+			if other, ok := seen[key]; ok {
+				t.Errorf("%s is bound to both %s and %s", key, other, a.name)
+			}
+			seen[key] = a.name
+		}
+	}
+
+	// the keys of each view are bound once, to something they do
+	for v, keys := range viewKeys {
+		seen := map[string]string{}
+		for _, b := range keys {
+			if b.run == nil {
+				t.Errorf("%s view: %s does nothing", viewNames[v], b.name)
+			}
+			for _, key := range b.keys {
+				if other, ok := seen[key]; ok {
+					t.Errorf("%s view: %s is bound to both %s and %s", viewNames[v], key, other, b.name)
+				}
+				seen[key] = b.name
+			}
+		}
+	}
+}
+
+func TestViewKey(t *testing.T) {
+	tasks := task.NewTasks()
+	due := time.Now()
+	for _, id := range []task.ID{"first", "second"} {
+		put(&tasks, id, "root", task.Task{Due: &due})
+	}
+	m := newApp(storage.NewJSON(filepath.Join(t.TempDir(), "tasks.json")), tasks)
+
+	// J moves the task in the agenda rather than leaving it to move-down
+	m.setView(agendaView)
+	if handled, err := m.viewKey("J"); !handled || err != nil {
+		t.Fatalf("agenda J: handled %v, %v", handled, err)
+	}
+	if got := m.all.Children["root"]; got[0] != "second" {
+		t.Errorf("agenda J: got %v", got)
+	}
+
+	// arrows stand in for hjkl
+	m.setView(calendarView)
+	day := m.calendar.day
+	m.viewKey("right")
+	if want := day.AddDate(0, 0, 1); !m.calendar.day.Equal(want) {
+		t.Errorf("calendar right: got %v, want %v", m.calendar.day, want)
+	}
+
+	// the charts keep the actions on tasks to themselves
+	m.setView(reportView)
+	if handled, _ := m.viewKey("t"); !handled {
+		t.Errorf("report view let toggle through")
+	}
+	if handled, _ := m.viewKey("v"); handled {
+		t.Errorf("report view kept the view switch to itself")
+	}
+}
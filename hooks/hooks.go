@@ -0,0 +1,200 @@
+// Package hooks runs executables when tasks change. Hooks live in a directory
+// and are named after the event they run for, with any suffix:
+//
+//	on-add  on-done  on-modify  on-move  on-remove
+//
+// A hook gets the change as JSON on stdin, before and after being null for
+// added and removed tasks:
+//
+//	{"event": "done", "id": "<id>", "parent": "<id>", "before": {...}, "after": {...}}
+//
+// It cancels the change by exiting with a non-zero status, giving the reason on
+// stderr, and changes the task by printing it on stdout as JSON. Hooks for the
+// same event run in name order, each getting the task as the last one left it.
+//
+// Hooks named after-add, after-done and so on run once the change is saved,
+// without holding up the TUI or the API, and get the change as it was made. They can neither
+// cancel nor change it, which makes them the place for slow work such as
+// posting to a chat:
+//
+//	title=$(jq -r .after.title)
+//	curl -s -d "done: $title" "$CHAT_URL"
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+// Runner runs the hooks in Dir
+type Runner struct {
+	Dir string
+	// Timeout is how long a hook may run before the change fails
+	Timeout time.Duration
+
+	mu sync.Mutex
+	// made are the changes Hook let through, for After
+	made []task.Change
+}
+
+type payload struct {
+	Event  task.Event `json:"event"`
+	ID     task.ID    `json:"id"`
+	Parent task.ID    `json:"parent"`
+	Before *task.Task `json:"before"`
+	After  *task.Task `json:"after"`
+}
+
+// FromEnv returns a runner configured from the environment, or nil if the
+// hooks directory does not exist:
+//
+//	TASKMAN_HOOKS         the hooks directory, taskman/hooks in the user config directory by default
+//	TASKMAN_HOOK_TIMEOUT  how long a hook may run, 5s by default
+func FromEnv() (*Runner, error) {
+	r := &Runner{Dir: os.Getenv("TASKMAN_HOOKS"), Timeout: 5 * time.Second}
+	if r.Dir == "" {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			return nil, nil
+		}
+		r.Dir = filepath.Join(config, "taskman", "hooks")
+	}
+	if timeout := os.Getenv("TASKMAN_HOOK_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("TASKMAN_HOOK_TIMEOUT: %w", err)
+		}
+		r.Timeout = d
+	}
+	if info, err := os.Stat(r.Dir); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// Hook runs the hooks for the event of c, and is meant to be set as task.Tasks.Hook
+func (r *Runner) Hook(c task.Change) (*task.Task, error) {
+	hooks, err := r.find("on-" + string(c.Event))
+	if err != nil {
+		return nil, err
+	}
+	after := c.After
+	for _, path := range hooks {
+		out, err := r.run(path, payload{c.Event, c.ID, c.Parent, c.Before, after})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		if len(bytes.TrimSpace(out)) == 0 || after == nil {
+			continue
+		}
+		// fields the hook leaves out keep their values
+		changed := *after
+		if err := json.Unmarshal(out, &changed); err != nil {
+			return nil, fmt.Errorf("%s: invalid task printed: %w", filepath.Base(path), err)
+		}
+		after = &changed
+	}
+
+	c.After = after
+	r.mu.Lock()
+	r.made = append(r.made, c)
+	r.mu.Unlock()
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+	return after, nil
+}
+
+// After takes the changes Hook let through since it was last called, and
+// returns what runs their after- hooks, or nil if there are none to run. It is
+// meant to be called once the changes are saved, and what it returns run
+// where it holds nothing up.
+func (r *Runner) After() func() error {
+	r.mu.Lock()
+	made := r.made
+	r.made = nil
+	r.mu.Unlock()
+	if len(made) == 0 {
+		return nil
+	}
+	if hooks, err := r.find("after-"); err == nil && len(hooks) == 0 {
+		return nil
+	}
+	return func() error {
+		for _, c := range made {
+			hooks, err := r.find("after-" + string(c.Event))
+			if err != nil {
+				return err
+			}
+			for _, path := range hooks {
+				if _, err := r.run(path, payload{c.Event, c.ID, c.Parent, c.Before, c.After}); err != nil {
+					return fmt.Errorf("%s: %w", filepath.Base(path), err)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// find lists the executables whose names start with prefix, in name order
+func (r *Runner) find(prefix string) ([]string, error) {
+	entries, err := os.ReadDir(r.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	hooks := []string{}
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), prefix) || e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.Mode()&0111 == 0 {
+			continue
+		}
+		hooks = append(hooks, filepath.Join(r.Dir, e.Name()))
+	}
+	sort.Strings(hooks)
+	return hooks, nil
+}
+
+// run runs a single hook, returning what it printed
+func (r *Runner) run(path string, p payload) ([]byte, error) {
+	input, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("timed out after %v", r.Timeout)
+	}
+	if err != nil {
+		if reason := strings.TrimSpace(stderr.String()); reason != "" {
+			return nil, errors.New(strings.SplitN(reason, "\n", 2)[0])
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
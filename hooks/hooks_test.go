@@ -0,0 +1,96 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+func runner(t *testing.T, scripts map[string]string) *Runner {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are shell scripts")
+	}
+	dir := t.TempDir()
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Runner{Dir: dir, Timeout: time.Second}
+}
+
+func TestRunner_Hook(t *testing.T) {
+	r := runner(t, map[string]string{
+		"on-add":          `echo '{"title":"tagged","tags":["new"]}'`,
+		"on-add.2-check":  `grep -q '"tags":\["new"\]' || { echo "not tagged" >&2; exit 1; }`,
+		"on-remove":       `echo "keep it" >&2; exit 1`,
+		"on-move":         `exec sleep 5`,
+		"on-modify.notes": `cat >/dev/null`,
+	})
+	after := task.Task{Title: "new", Priority: "A"}
+
+	got, err := r.Hook(task.Change{Event: task.EventAdd, ID: "a", Parent: "root", After: &after})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "tagged" || got.Priority != "A" {
+		t.Errorf("got %+v, want the printed fields over the rest", got)
+	}
+
+	got, err = r.Hook(task.Change{Event: task.EventModify, ID: "a", Before: &after, After: &after})
+	if err != nil || got.Title != "new" {
+		t.Errorf("hook printing nothing changed the task: %+v, %v", got, err)
+	}
+
+	_, err = r.Hook(task.Change{Event: task.EventRemove, ID: "a", Before: &after})
+	if err == nil || !strings.Contains(err.Error(), "keep it") {
+		t.Errorf("got %v, want the reason from stderr", err)
+	}
+
+	_, err = r.Hook(task.Change{Event: task.EventMove, ID: "a", Before: &after, After: &after})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("got %v, want a timeout", err)
+	}
+}
+
+func TestRunner_After(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+	r := runner(t, map[string]string{
+		"on-add":     `echo '{"title":"tagged"}'`,
+		"on-remove":  `exit 1`,
+		"after-add":  `cat >>` + log,
+		"after-done": `echo done >>` + log + `; echo "chat is down" >&2; exit 1`,
+	})
+	after := task.Task{Title: "new"}
+
+	if r.After() != nil {
+		t.Errorf("hooks to run with nothing changed")
+	}
+	r.Hook(task.Change{Event: task.EventAdd, ID: "a", Parent: "root", After: &after})
+	r.Hook(task.Change{Event: task.EventRemove, ID: "a", Before: &after})
+	run := r.After()
+	if _, err := os.Stat(log); err == nil {
+		t.Fatal("after- hooks ran before they were asked to")
+	}
+	if err := run(); err != nil {
+		t.Fatal(err)
+	}
+	// the task as the on- hooks left it, and nothing for the rejected remove
+	got, _ := os.ReadFile(log)
+	if s := string(got); strings.Count(s, `"event"`) != 1 || !strings.Contains(s, `"after":{"title":"tagged"`) {
+		t.Errorf("got %s", got)
+	}
+	if r.After() != nil {
+		t.Errorf("the same changes were handed out twice")
+	}
+
+	r.Hook(task.Change{Event: task.EventDone, ID: "a", Before: &after, After: &after})
+	if err := r.After()(); err == nil || !strings.Contains(err.Error(), "chat is down") {
+		t.Errorf("got %v, want the reason from stderr", err)
+	}
+}
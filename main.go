@@ -1,26 +1,96 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/td0m/taskman/hooks"
+	"github.com/td0m/taskman/storage"
 )
 
 func main() {
-	a := newApp()
+	check(run())
+}
+
+func run() error {
+	store := storage.NewJSON("./tasks.json")
+	runner, err := hooks.FromEnv()
+	if err != nil {
+		return err
+	}
+	if runner != nil {
+		store.Hook, store.After = runner.Hook, runner.After
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "help", "-h", "--help":
+			usage(os.Stdout)
+			return nil
+		}
+		c, ok := commands[os.Args[1]]
+		if !ok {
+			usage(os.Stderr)
+			return fmt.Errorf("unknown command %q", os.Args[1])
+		}
+		err := c.run(store, os.Args[2:], os.Stdout)
+		// usage has already been printed by the flag set
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		if err != nil || store.After == nil {
+			return err
+		}
+		// there is nothing left to hold up, the command is done
+		if after := store.After(); after != nil {
+			return after()
+		}
+		return nil
+	}
+
+	data, err := store.Fetch()
+	if errors.Is(err, storage.ErrCorrupt) {
+		data, err = recoverStore(store, err, os.Stdin, os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	a := newApp(store, data)
+	if a.syncer, err = newSyncer(store); err != nil {
+		return err
+	}
+	a.reminder = newReminder(store)
+	// the TUI works without it, only out of reach of the other commands, but
+	// not next to another one that would write over its changes
+	a.control, err = listenControl(store)
+	if errors.Is(err, errAlreadyOpen) {
+		return err
+	}
+	if err != nil {
+		a.warning = fmt.Errorf("no control socket: %w", err)
+	} else {
+		defer a.control.Close()
+	}
 	p := tea.NewProgram(a)
 
 	// enable full terminal mode
 	p.EnterAltScreen()
 	defer p.ExitAltScreen()
 
-	// enable mouse (for scrolling)
+	// enable mouse (for clicking and scrolling)
 	p.EnableMouseAllMotion()
 	defer p.DisableMouseAllMotion()
 
-	check(p.Start())
+	return p.Start()
 }
 
 func check(err error) {
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, "taskman:", err)
+		os.Exit(1)
 	}
 }
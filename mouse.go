@@ -0,0 +1,137 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/td0m/taskman/ui"
+)
+
+// wheelLines is how far the wheel scrolls at a time
+const wheelLines = 3
+
+// mouse clicks tabs and tasks, and scrolls with the wheel. Clicking the icon
+// of a task marks it as done, or unfolds it if it is the arrow of a folded
+// task, and clicking the count of its subtasks folds or unfolds it.
+func (m *app) mouse(msg tea.MouseMsg) error {
+	// holding the button down reports more presses as the mouse moves, which
+	// only count as one click
+	pressed := msg.Type == tea.MouseLeft && !m.pressed
+	m.pressed = msg.Type == tea.MouseLeft
+	switch {
+	case m.mode == helpMode || m.mode == normalMode && m.view.chart():
+		switch msg.Type {
+		case tea.MouseWheelUp:
+			m.viewport.LineUp(wheelLines)
+		case tea.MouseWheelDown:
+			m.viewport.LineDown(wheelLines)
+		}
+		return nil
+	case m.mode != normalMode:
+		return nil
+	}
+	if i, ok := m.tabs.At(msg.X, msg.Y); ok && pressed {
+		if i != m.tabs.Value() {
+			m.showTab(i)
+		}
+		return nil
+	}
+	switch m.view {
+	case calendarView:
+		return m.calendarMouse(msg)
+	case boardView:
+		return nil
+	}
+	switch msg.Type {
+	case tea.MouseWheelUp:
+		m.scroll(-wheelLines)
+	case tea.MouseWheelDown:
+		m.scroll(wheelLines)
+	case tea.MouseLeft:
+		top := headerHeight
+		if m.view == timelineView {
+			top++
+		}
+		i, ok := m.lineAt(msg.Y - top + m.viewport.YOffset)
+		if !ok || !pressed {
+			return nil
+		}
+		m.setCursor(i)
+		return m.click(msg.X)
+	}
+	return nil
+}
+
+// lineAt returns the task on a line of the tasks, false for the headings and
+// blank lines between them
+func (m app) lineAt(line int) (int, bool) {
+	start := 0
+	for i := range m.visible {
+		size := m.sizeOf(i)
+		if line < start+size {
+			return i, line == start+size-1
+		}
+		start += size
+	}
+	return 0, false
+}
+
+// click acts on what is at x on the line of the task at the cursor
+func (m *app) click(x int) error {
+	if !m.view.inline() {
+		return nil
+	}
+	p := m.atCursor()
+	id := getID(p)
+	t := m.all.Nodes[id]
+	indent := 0
+	if m.view == treeView {
+		indent = 2 * (len(p) - 2)
+	}
+	icon := lipgloss.Width(ui.RenderIcon(t))
+	switch {
+	case x >= indent && x < indent+icon:
+		if t.Done == nil && m.folded(id) {
+			err := m.setFolded(id, false)
+			m.updateVisible()
+			return err
+		}
+		return m.toggle("")
+	case m.view == treeView:
+		p := m.tally()[id]
+		if p.total == 0 {
+			return nil
+		}
+		start := indent + icon + lipgloss.Width(t.Title)
+		if x >= start && x < start+lipgloss.Width(ui.RenderProgress(p.done, p.total, m.folded(id))) {
+			err := m.setFolded(id, !m.folded(id))
+			m.updateVisible()
+			return err
+		}
+	}
+	return nil
+}
+
+// scroll moves the tasks by n lines, taking the cursor along when it would
+// go out of sight
+func (m *app) scroll(n int) {
+	// the line each task is on
+	lines, start := make([]int, len(m.visible)), 0
+	for i := range m.visible {
+		start += m.sizeOf(i)
+		lines[i] = start - 1
+	}
+	offset := clamp(m.viewport.YOffset+n, 0, max(start-m.viewport.Height, 0))
+	m.viewport.YOffset = offset
+	if len(lines) == 0 {
+		return
+	}
+	cursor := m.cursor
+	for cursor < len(lines)-1 && lines[cursor] < offset {
+		cursor++
+	}
+	for cursor > 0 && lines[cursor] >= offset+m.viewport.Height {
+		cursor--
+	}
+	m.setCursor(cursor)
+	m.viewport.YOffset = offset
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+func TestMouse(t *testing.T) {
+	tasks := task.NewTasks()
+	put(&tasks, "release", "root", task.Task{})
+	put(&tasks, "notes", "release", task.Task{})
+	put(&tasks, "build", "release", task.Task{})
+	put(&tasks, "chores", "root", task.Task{})
+	model, _ := newApp(storage.NewJSON(t.TempDir()+"/tasks.json"), tasks).Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	m := model.(app)
+	click := func(x, y int) {
+		for _, typ := range []tea.MouseEventType{tea.MouseLeft, tea.MouseLeft, tea.MouseRelease} {
+			model, _ := m.Update(tea.MouseMsg{X: x, Y: y, Type: typ})
+			m = model.(app)
+		}
+	}
+	// clicks a line of the tasks, wherever they are scrolled to
+	clickLine := func(x, line int) {
+		click(x, headerHeight+line-m.viewport.YOffset)
+	}
+
+	// blank lines above release and chores
+	clickLine(10, 5)
+	if got := getID(m.atCursor()); got != "chores" {
+		t.Errorf("clicked chores, cursor on %s", got)
+	}
+	clickLine(10, 4)
+	if got := getID(m.atCursor()); got != "chores" {
+		t.Errorf("clicked a blank line, cursor moved to %s", got)
+	}
+	clickLine(10, 2)
+	if got := getID(m.atCursor()); got != "notes" {
+		t.Errorf("clicked notes, cursor on %s", got)
+	}
+
+	// the icon is indented along with the subtask, and only clicked once
+	clickLine(3, 2)
+	if m.all.Nodes["notes"].Done == nil {
+		t.Errorf("clicking the icon should mark notes as done")
+	}
+
+	// the count of subtasks folds, the arrow unfolds
+	clickLine(len("   release")+4, 1)
+	if !m.folded("release") || m.indexOf("notes") >= 0 {
+		t.Errorf("clicking the count should fold release")
+	}
+	clickLine(1, 1)
+	if m.folded("release") || m.all.Nodes["release"].Done != nil {
+		t.Errorf("clicking the arrow should unfold release, not mark it done")
+	}
+
+	// All | Inbox | Today, on the second line of the header
+	click(len(" All | I"), 1)
+	if got := m.tabs.Value(); got != 1 {
+		t.Errorf("clicked Inbox, got tab %d", got)
+	}
+}
+
+func TestWheel(t *testing.T) {
+	tasks := task.NewTasks()
+	for _, id := range []task.ID{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		put(&tasks, id, "root", task.Task{})
+	}
+	m := newApp(storage.NewJSON(t.TempDir()+"/tasks.json"), tasks)
+	m.viewport.Height = 4
+	m.updateVisible()
+
+	tests := []struct {
+		n, offset int
+		cursor    task.ID
+	}{
+		// the first task has a blank line above it
+		{3, 4, "d"},
+		{-1, 3, "d"},
+		{-2, 1, "d"},
+		{-5, 0, "c"},
+		{20, 5, "e"},
+	}
+	m.setCursor(3)
+	for _, tt := range tests {
+		m.scroll(tt.n)
+		if got := getID(m.atCursor()); m.viewport.YOffset != tt.offset || got != tt.cursor {
+			t.Errorf("scroll %d: got offset %d on %s, want %d on %s", tt.n, m.viewport.YOffset, got, tt.offset, tt.cursor)
+		}
+	}
+}
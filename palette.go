@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/td0m/taskman/ui"
+)
+
+// palette is the state of the command palette, which searches the actions
+// by what is typed into the text input
+type palette struct {
+	// selected is the match that enter runs
+	selected int
+}
+
+// paletteHeight is how many matches are listed at most
+const paletteHeight = 8
+
+var (
+	errNoAction = errors.New("no action matches")
+
+	paletteItem     = lipgloss.NewStyle().Foreground(ui.Secondary)
+	paletteSelected = lipgloss.NewStyle().Background(ui.Faded).Foreground(ui.Background)
+)
+
+// fuzzy scores how well pattern matches s, with its letters in order but not
+// necessarily next to each other, favouring runs of letters and the starts of
+// words. It is false if the letters are not all there.
+func fuzzy(pattern, s string) (int, bool) {
+	runes := []rune(strings.ToLower(s))
+	score, last, i := 0, -2, 0
+	for _, r := range strings.ToLower(pattern) {
+		for i < len(runes) && runes[i] != r {
+			i++
+		}
+		if i == len(runes) {
+			return 0, false
+		}
+		score++
+		if i == last+1 {
+			score += 2
+		}
+		if i == 0 || strings.ContainsRune(" -_/", runes[i-1]) {
+			score += 3
+		}
+		last = i
+		i++
+	}
+	return score, true
+}
+
+// splitInput splits what is typed into the palette into the name of an action
+// and its arguments
+func splitInput(input string) (string, string) {
+	input = strings.TrimLeft(input, " ")
+	if i := strings.IndexByte(input, ' '); i >= 0 {
+		return input[:i], strings.TrimSpace(input[i+1:])
+	}
+	return input, ""
+}
+
+// matches lists the actions whose names match what is typed, best first
+func (m app) matches() []action {
+	name, _ := splitInput(m.textinput.Value())
+	type match struct {
+		action
+		score int
+	}
+	found := []match{}
+	for _, a := range actions {
+		score, ok := fuzzy(name, a.name)
+		if !ok || a.name == "palette" {
+			continue
+		}
+		if a.name == name {
+			score += 100
+		}
+		found = append(found, match{a, score})
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].score > found[j].score })
+	matches := make([]action, len(found))
+	for i, f := range found {
+		matches[i] = f.action
+	}
+	return matches
+}
+
+func (m *app) openPalette() {
+	m.mode = paletteMode
+	m.palette.selected = 0
+	m.textinput.SetValue("")
+	m.textinput.Width = 1
+}
+
+// paletteKey handles the keys typed into the palette
+func (m *app) paletteKey(msg tea.KeyMsg) tea.Cmd {
+	matches := m.matches()
+	switch msg.String() {
+	case tea.KeyEnter.String():
+		m.mode = normalMode
+		if len(matches) == 0 {
+			m.warning = errNoAction
+			return nil
+		}
+		_, args := splitInput(m.textinput.Value())
+		return m.perform(matches[clamp(m.palette.selected, 0, len(matches)-1)], args)
+	case tea.KeyUp.String(), "ctrl+p":
+		m.palette.selected = max(m.palette.selected-1, 0)
+	case tea.KeyDown.String(), "ctrl+n":
+		m.palette.selected = clamp(m.palette.selected+1, 0, len(matches)-1)
+	case tea.KeyTab.String():
+		// completes the name of the selected action
+		if len(matches) > 0 {
+			a := matches[clamp(m.palette.selected, 0, len(matches)-1)]
+			m.textinput.SetValue(a.name + " ")
+			m.textinput.Width = len(m.textinput.Value()) + 1
+			m.textinput.SetCursor(m.textinput.Width)
+			m.palette.selected = 0
+		}
+	default:
+		before, _ := splitInput(m.textinput.Value())
+		var cmd tea.Cmd
+		m.textinput, cmd = m.textinput.Update(msg)
+		m.textinput.Width = len(m.textinput.Value()) + 1
+		if name, _ := splitInput(m.textinput.Value()); name != before {
+			m.palette.selected = 0
+		}
+		return cmd
+	}
+	return nil
+}
+
+// renderPalette lists the matching actions over the bottom of the tasks
+func (m app) renderPalette(body string) string {
+	lines := strings.Split(body, "\n")
+	matches := m.matches()
+	n := min(min(len(matches), paletteHeight), len(lines))
+	// the selected match stays in sight
+	start := max(m.palette.selected-n+1, 0)
+	end := start + n
+	width := m.viewport.Width
+	for i, a := range matches[start:end] {
+		key := ""
+		if len(a.keys) > 0 {
+			key = a.keys[0]
+		}
+		name := runewidth.Truncate(" "+a.name+" "+a.args, 26, "…")
+		help := runewidth.Truncate(a.help, max(width-26-len(key)-2, 0), "…")
+		line := runewidth.FillRight(name, 26) + help
+		line = runewidth.FillRight(line, max(width-len(key)-1, 0)) + key + " "
+		style := paletteItem
+		if start+i == m.palette.selected {
+			style = paletteSelected
+		}
+		lines[len(lines)-(end-start)+i] = style.Render(line)
+	}
+	return strings.Join(lines, "\n")
+}
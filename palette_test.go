@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestFuzzy(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		ok         bool
+	}{
+		{"", "due", true},
+		{"mt", "move-to", true},
+		{"MOVE", "move-to", true},
+		{"tm", "move-to", false},
+		{"rel", "Release notes", true},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzy(tt.pattern, tt.s); ok != tt.ok {
+			t.Errorf("fuzzy(%q, %q): got %v, want %v", tt.pattern, tt.s, ok, tt.ok)
+		}
+	}
+
+	// runs of letters and starts of words do better than scattered letters
+	better := [][3]string{
+		{"mt", "move-to", "make it"},
+		{"rel", "release", "real estate"},
+		{"in", "indent", "paint"},
+	}
+	for _, b := range better {
+		s1, _ := fuzzy(b[0], b[1])
+		s2, _ := fuzzy(b[0], b[2])
+		if s1 <= s2 {
+			t.Errorf("%q: %q scored %d, no more than %q with %d", b[0], b[1], s1, b[2], s2)
+		}
+	}
+}
+
+func TestSplitInput(t *testing.T) {
+	tests := []struct {
+		input, name, args string
+	}{
+		{"due", "due", ""},
+		{"due next fri", "due", "next fri"},
+		{" move-to  release ", "move-to", "release"},
+	}
+	for _, tt := range tests {
+		if name, args := splitInput(tt.input); name != tt.name || args != tt.args {
+			t.Errorf("%q: got %q %q, want %q %q", tt.input, name, args, tt.name, tt.args)
+		}
+	}
+}
@@ -27,6 +27,9 @@ var (
 type Model struct {
 	i     textinput.Model
 	value *time.Time
+
+	// Prefix names the date being entered
+	Prefix string
 }
 
 func NewModel() Model {
@@ -35,7 +38,8 @@ func NewModel() Model {
 	i.CharLimit = 20
 	i.Prompt = ""
 	return Model{
-		i: i,
+		i:      i,
+		Prefix: "due",
 	}
 }
 
@@ -52,7 +56,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		m.i, cmd = m.i.Update(msg)
-		m.value = parseDate(m.i.Value())
+		m.value = Parse(m.i.Value())
 		return m, cmd
 	}
 	return m, nil
@@ -67,8 +71,7 @@ func (m *Model) View() string {
 	} else if m.value != nil {
 		indicator = checkmark + " " + format(*m.value)
 	}
-	prefix := "due"
-	return lipgloss.NewStyle().Foreground(faded).Render(prefix+": ") + m.i.View() + "" + indicator
+	return lipgloss.NewStyle().Foreground(faded).Render(m.Prefix+": ") + m.i.View() + "" + indicator
 }
 
 func (m *Model) Value() *time.Time {
@@ -83,7 +86,13 @@ func (m *Model) SetValue(t *time.Time) {
 	m.i.SetValue((*t).Format(formats[0]))
 }
 
-func parseDate(s string) *time.Time {
+// Parse reads a date the way it is typed into the input, such as "tomorrow",
+// "fri", "next fri", "in 2 weeks" or "21 feb", returning nil if it is not one
+func Parse(s string) *time.Time {
+	return parse(s, time.Now().Truncate(time.Hour*24))
+}
+
+func parse(s string, today time.Time) *time.Time {
 	// s = strings.ToLower(s)
 	// if s == "" {
 	// 	return nil
@@ -94,7 +103,16 @@ func parseDate(s string) *time.Time {
 	// 		return &t
 	// 	}
 	// }
-	today := time.Now().Truncate(time.Hour * 24)
+	if rest := strings.TrimPrefix(s, "next "); rest != s && rest != "" {
+		// the day in the week after this one, weeks starting on monday
+		d, ok := parseWeekday(rest)
+		if !ok {
+			return nil
+		}
+		monday := today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7)
+		next := monday.AddDate(0, 0, (int(d)+6)%7)
+		return &next
+	}
 	{
 		for i, fmt := range []string{"today", "tomorrow"} {
 			end := min(len(s), len(fmt))
@@ -104,13 +122,9 @@ func parseDate(s string) *time.Time {
 			}
 		}
 	}
-	for i := time.Sunday; i <= time.Saturday; i++ {
-		fmt := strings.ToLower(i.String())
-		end := min(len(s), len(fmt)-1)
-		if s == fmt[:end] {
-			d := nextWeekday(today, i)
-			return &d
-		}
+	if day, ok := parseWeekday(s); ok {
+		d := nextWeekday(today, day)
+		return &d
 	}
 	duration, err := parseRelative(s)
 	if err == nil {
@@ -126,6 +140,18 @@ func parseDate(s string) *time.Time {
 	return nil
 }
 
+// parseWeekday reads the start of the name of a day of the week
+func parseWeekday(s string) (time.Weekday, bool) {
+	for i := time.Sunday; i <= time.Saturday; i++ {
+		fmt := strings.ToLower(i.String())
+		end := min(len(s), len(fmt))
+		if s == fmt[:end] {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
 func nextWeekday(t time.Time, d time.Weekday) time.Time {
 	day := d - t.Weekday()
 	if day < 0 {
@@ -68,3 +68,31 @@ func Test_parseAbsolute(t *testing.T) {
 		})
 	}
 }
+
+func Test_parse(t *testing.T) {
+	// a monday
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"tomorrow", today.AddDate(0, 0, 1)},
+		{"mon", today},
+		{"fri", today.AddDate(0, 0, 4)},
+		{"next fri", today.AddDate(0, 0, 11)},
+		{"next mon", today.AddDate(0, 0, 7)},
+		{"next sunday", today.AddDate(0, 0, 13)},
+		{"in 2 weeks", today.AddDate(0, 0, 14)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := parse(tt.input, today)
+			if got == nil || !got.Equal(tt.want) {
+				t.Errorf("%s\ngot:  %v\nwant: %v", tt.input, got, tt.want.Format("02-01-2006"))
+			}
+		})
+	}
+	if got := parse("next week", today); got != nil {
+		t.Errorf("next week: got %v, want nothing", got)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+var errAborted = errors.New("aborted")
+
+// recoverStore asks what to do with a task file that can not be decoded:
+// salvage what is left of it or replace it with one of its backups
+func recoverStore(store *storage.JSONBackend, cause error, in io.Reader, out io.Writer) (task.Tasks, error) {
+	backups := store.Backups()
+
+	fmt.Fprintf(out, "%v\n\n", cause)
+	fmt.Fprintln(out, "  r  repair, keeping whatever can still be read")
+	for i, path := range backups {
+		modified := ""
+		if info, err := os.Stat(path); err == nil {
+			modified = info.ModTime().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(out, "  %d  open backup %s (%s)\n", i+1, path, modified)
+	}
+	fmt.Fprintln(out, "  q  quit")
+	fmt.Fprintf(out, "\nthe damaged file will be kept as %s.corrupt\n> ", store.File())
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return task.Tasks{}, err
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "r" {
+		return store.Repair()
+	}
+	if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(backups) {
+		return store.Restore(backups[i-1])
+	}
+	return task.Tasks{}, errAborted
+}
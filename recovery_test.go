@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+func TestRecoverStore(t *testing.T) {
+	damaged := `{"version":1,"nodes":{"root":{},"a":{"title":"a"},"b":{"title":7}},"children":{"root":["a"]},"parent":{"a":"root"}}`
+	backup := `{"version":1,"nodes":{"root":{},"old":{"title":"old"}},"children":{"root":["old"]},"parent":{"old":"root"}}`
+	tests := []struct {
+		answer string
+		want   string
+		err    error
+	}{
+		{"r\n", "a", nil},
+		{"1\n", "old", nil},
+		{"2\n", "", errAborted},
+		{"q\n", "", errAborted},
+		{"", "", errAborted},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), "tasks.json")
+		os.WriteFile(file, []byte(damaged), 0600)
+		os.WriteFile(file+".bak", []byte(backup), 0600)
+		store := storage.NewJSON(file)
+
+		out := &strings.Builder{}
+		tasks, err := recoverStore(store, storage.ErrCorrupt, strings.NewReader(tt.answer), out)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: got %v, want %v", tt.answer, err, tt.err)
+			continue
+		}
+		if !strings.Contains(out.String(), "1  open backup "+file+".bak") {
+			t.Errorf("%q: backup not offered:\n%s", tt.answer, out)
+		}
+		if tt.err != nil {
+			if data, _ := os.ReadFile(file); string(data) != damaged {
+				t.Errorf("%q: task file changed without being asked to", tt.answer)
+			}
+			continue
+		}
+		if _, found := tasks.Nodes[task.ID(tt.want)]; !found {
+			t.Errorf("%q: got %+v, want %s", tt.answer, tasks.Nodes, tt.want)
+		}
+	}
+}
+
+func TestQuitAfterFailure(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tasks.json")
+	store := storage.NewJSON(file)
+	tasks, _ := store.Fetch()
+	m := newApp(store, tasks)
+	m.fail(errors.New("boom"))
+
+	// changes made since the failure are not written over the task file...
+	m.perform(keymap["o"], "")
+	m.all.SetTitle(getID(m.atCursor()), "after")
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd == nil || cmd() != tea.Quit() {
+		t.Fatalf("ctrl+c should quit")
+	}
+	if saved, _ := store.Fetch(); len(saved.Nodes) != 1 {
+		t.Errorf("task file written over after a failure: %+v", saved.Nodes)
+	}
+	// ...but saved in the emergency copy when quitting
+	data, _ := os.ReadFile(file + ".emergency")
+	if !strings.Contains(string(data), `"after"`) {
+		t.Errorf("emergency copy lacks the change made after the failure:\n%s", data)
+	}
+}
@@ -0,0 +1,66 @@
+package remind
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Notifier shows a reminder to the user
+type Notifier interface {
+	Notify(a Alert) error
+}
+
+// NewNotifier returns the notifier named by s: "notify-send" for a desktop
+// notification, "bell" to ring the terminal bell, or else a shell command that
+// gets the reminder in TASKMAN_ID, TASKMAN_TITLE and TASKMAN_MESSAGE.
+func NewNotifier(s string) Notifier {
+	switch s {
+	case "notify-send":
+		return NotifySend{}
+	case "bell":
+		return Bell{os.Stderr}
+	}
+	return Command(s)
+}
+
+// NotifySend shows a desktop notification with notify-send, which goes through D-Bus
+type NotifySend struct{}
+
+func (NotifySend) Notify(a Alert) error {
+	msg := a.Message(time.Now())
+	urgency := "normal"
+	if msg == "overdue" || msg == "due today" {
+		urgency = "critical"
+	}
+	body := msg + "\nsnooze with: taskman snooze " + string(a.ID)
+	return exec.Command("notify-send", "--app-name=taskman", "--urgency="+urgency, a.Task.Title, body).Run()
+}
+
+// Bell rings the terminal bell on Out
+type Bell struct {
+	Out io.Writer
+}
+
+func (b Bell) Notify(Alert) error {
+	_, err := fmt.Fprint(b.Out, "\a")
+	return err
+}
+
+// Command runs a shell command
+type Command string
+
+func (c Command) Notify(a Alert) error {
+	cmd := exec.Command("sh", "-c", string(c))
+	cmd.Env = append(os.Environ(),
+		"TASKMAN_ID="+string(a.ID),
+		"TASKMAN_TITLE="+a.Task.Title,
+		"TASKMAN_MESSAGE="+a.Message(time.Now()),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v: %s", c, err, out)
+	}
+	return nil
+}
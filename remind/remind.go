@@ -0,0 +1,308 @@
+// Package remind works out when to be reminded of due tasks. Reminders are
+// written relative to the due date of a task:
+//
+//	morning of      9:00 on the due date
+//	day before      9:00 the day before
+//	evening before  18:00 the day before
+//	at 14:30        that time on the due date
+//	1h before       before the end of the due date, with m, h, d or w units
+//
+// Tasks without reminders of their own get Defaults.
+package remind
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+// Defaults are the reminders of tasks that have none
+var Defaults = []string{"morning of"}
+
+var ErrBadReminder = errors.New(`reminders look like "1h before", "morning of" or "at 14:30"`)
+
+// Reminder is a parsed reminder
+type Reminder struct {
+	// days and clock place the reminder at a time of day, relative to the due date
+	days  int
+	clock time.Duration
+	// before is relative to the end of the due date instead, if set
+	before time.Duration
+}
+
+var named = map[string]Reminder{
+	"morning of":     {0, 9 * time.Hour, 0},
+	"day before":     {-1, 9 * time.Hour, 0},
+	"evening before": {-1, 18 * time.Hour, 0},
+}
+
+// Parse reads a reminder
+func Parse(s string) (Reminder, error) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	if r, ok := named[s]; ok {
+		return r, nil
+	}
+	if before := strings.TrimSuffix(s, " before"); before != s {
+		d, err := ParseDuration(before)
+		if err != nil || d <= 0 {
+			return Reminder{}, ErrBadReminder
+		}
+		return Reminder{before: d}, nil
+	}
+	t, err := time.Parse("15:04", strings.TrimPrefix(s, "at "))
+	if err != nil {
+		return Reminder{}, ErrBadReminder
+	}
+	return Reminder{clock: time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute}, nil
+}
+
+// ParseDuration is time.ParseDuration with days and weeks, such as 1d or 2w
+func ParseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n := strings.TrimSuffix(s, suffix); n != s {
+			i, err := strconv.Atoi(n)
+			return time.Duration(i) * unit, err
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// At returns when the reminder goes off for a task due on due. Due dates are
+// days, kept as midnight UTC, and reminders go off in local time on that day.
+func (r Reminder) At(due time.Time) time.Time {
+	day := localDay(due)
+	if r.before > 0 {
+		return day.AddDate(0, 0, 1).Add(-r.before)
+	}
+	return day.AddDate(0, 0, r.days).Add(r.clock)
+}
+
+func localDay(due time.Time) time.Time {
+	due = due.UTC()
+	return time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// Validate checks that every reminder in list can be parsed
+func Validate(list []string) error {
+	for _, s := range list {
+		if _, err := Parse(s); err != nil {
+			return fmt.Errorf("%q: %w", s, err)
+		}
+	}
+	return nil
+}
+
+// times returns when the reminders of t go off, skipping the invalid ones
+func times(t task.Task) []time.Time {
+	list := t.Reminders
+	if len(list) == 0 {
+		list = Defaults
+	}
+	at := []time.Time{}
+	for _, s := range list {
+		if r, err := Parse(s); err == nil {
+			at = append(at, r.At(*t.Due))
+		}
+	}
+	return at
+}
+
+// State is which reminders went off and which tasks were snoozed. It is kept
+// apart from the tasks, so that reminding never writes the task file.
+type State struct {
+	Reminded map[task.ID]time.Time `json:"reminded"`
+	Snoozed  map[task.ID]time.Time `json:"snoozed"`
+}
+
+// LoadState reads the state saved at path, which may not exist yet
+func LoadState(path string) (State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		state, _ := parseState(nil)
+		return state, err
+	}
+	return parseState(data)
+}
+
+func parseState(data []byte) (State, error) {
+	state := State{}
+	var err error
+	if data != nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if state.Reminded == nil {
+		state.Reminded = map[task.ID]time.Time{}
+	}
+	if state.Snoozed == nil {
+		state.Snoozed = map[task.ID]time.Time{}
+	}
+	return state, err
+}
+
+// ErrBusy is returned by UpdateState when another process holds on to the
+// state for too long
+var ErrBusy = errors.New("reminder state is in use elsewhere, try again")
+
+// lockWait is how long UpdateState waits for another process to be done with
+// the state, and staleLock how old a lock is once its process is taken to be
+// gone
+var (
+	lockWait  = 5 * time.Second
+	staleLock = time.Minute
+)
+
+// UpdateState applies change to the state saved at path. The TUI and the
+// commands all change it, so it is locked meanwhile by a file next to it.
+func UpdateState(path string, change func(*State)) error {
+	unlock, err := lock(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	state, err := parseState(data)
+	if err != nil {
+		return err
+	}
+	change(&state)
+	return state.Save(path)
+}
+
+// lock creates the lock file at path, waiting for whoever holds it, and
+// returns what removes it
+func lock(path string) (func(), error) {
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		// left behind by a process that did not get to remove it
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Since(start) > lockWait {
+			return nil, ErrBusy
+		}
+	}
+}
+
+// Save writes the state to path, over whatever is there
+func (s State) Save(path string) error {
+	tmp, err := s.write(path)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// write writes the state to a new file next to path, readable by the user
+// only, and returns its name
+func (s State) write(path string) (string, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// Alert is a reminder that went off
+type Alert struct {
+	ID   task.ID
+	Task task.Task
+}
+
+// Message says when the task is due, relative to now
+func (a Alert) Message(now time.Time) string {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	day := localDay(*a.Task.Due)
+	switch days := int(day.Sub(today).Hours()+12) / 24; {
+	case day.Before(today):
+		return "overdue"
+	case days == 0:
+		return "due today"
+	case days == 1:
+		return "due tomorrow"
+	default:
+		return "due " + day.Format("Mon Jan 2")
+	}
+}
+
+// Check returns the tasks with a reminder that went off since the last check,
+// or with a snooze that ran out, and forgets about those that are gone or done.
+// Every task is reminded of at most once per check. Alerts are not recorded
+// until they are handed to Record, once delivered.
+func Check(tasks task.Tasks, state *State, now time.Time) []Alert {
+	alerts := []Alert{}
+	ids := []task.ID{}
+	for id := range tasks.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		t := tasks.Nodes[id]
+		if t.Due == nil || t.Done != nil {
+			continue
+		}
+		at := times(t)
+		if snoozed, ok := state.Snoozed[id]; ok {
+			if now.Before(snoozed) {
+				continue
+			}
+			at = append(at, snoozed)
+		}
+		last := state.Reminded[id]
+		for _, a := range at {
+			if a.After(last) && !a.After(now) {
+				alerts = append(alerts, Alert{id, t})
+				break
+			}
+		}
+	}
+
+	// forget about tasks that are gone or done
+	for _, m := range []map[task.ID]time.Time{state.Reminded, state.Snoozed} {
+		for id := range m {
+			if t, found := tasks.Nodes[id]; !found || t.Done != nil {
+				delete(m, id)
+			}
+		}
+	}
+	return alerts
+}
+
+// Record notes that the reminder of a task was delivered at now, which ends
+// its snooze
+func (s *State) Record(id task.ID, now time.Time) {
+	s.Reminded[id] = now
+	delete(s.Snoozed, id)
+}
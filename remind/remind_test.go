@@ -0,0 +1,136 @@
+package remind
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+func TestReminder_At(t *testing.T) {
+	due := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	day := func(d, h, m int) time.Time { return time.Date(2026, 10, d, h, m, 0, 0, time.Local) }
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"morning of", day(20, 9, 0)},
+		{"Evening  before", day(19, 18, 0)},
+		{"at 14:30", day(20, 14, 30)},
+		{"7:05", day(20, 7, 5)},
+		{"1h before", day(20, 23, 0)},
+		{"1h30m before", day(20, 22, 30)},
+		{"2d before", day(19, 0, 0)},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+			continue
+		}
+		if got := r.At(due); !got.Equal(tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "soon", "0h before", "at 25:00", "-1h before"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	due := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	tasks := task.NewTasks()
+	tasks.Nodes["a"] = task.Task{Title: "a", Due: &due, Reminders: []string{"evening before", "morning of"}}
+	tasks.Nodes["b"] = task.Task{Title: "b", Due: &due}
+	tasks.Nodes["c"] = task.Task{Title: "no due date"}
+	state, _ := LoadState("")
+	at := func(d, h int) time.Time { return time.Date(2026, 10, d, h, 0, 0, 0, time.Local) }
+
+	steps := []struct {
+		now  time.Time
+		want []task.ID
+	}{
+		{at(19, 12), nil},
+		{at(19, 18), []task.ID{"a"}},
+		{at(19, 20), nil},
+		{at(20, 9), []task.ID{"a", "b"}},
+		{at(20, 10), nil},
+	}
+	for i, step := range steps {
+		if i == 4 {
+			state.Snoozed["b"] = at(20, 11)
+		}
+		alerts := Check(tasks, &state, step.now)
+		if len(alerts) != len(step.want) {
+			t.Fatalf("at %v: got %v, want %v", step.now, alerts, step.want)
+		}
+		for j, a := range alerts {
+			if a.ID != step.want[j] {
+				t.Errorf("at %v: got %v, want %v", step.now, alerts, step.want)
+			}
+			state.Record(a.ID, step.now)
+		}
+	}
+
+	// snoozed, and not delivered the first time
+	for i := 0; i < 2; i++ {
+		if alerts := Check(tasks, &state, at(20, 11)); len(alerts) != 1 || alerts[0].ID != "b" {
+			t.Errorf("snooze did not run out: %v", alerts)
+		}
+	}
+	state.Record("b", at(20, 11))
+	if len(state.Snoozed) != 0 {
+		t.Errorf("snooze was kept: %v", state.Snoozed)
+	}
+}
+
+func TestUpdateState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json.remind")
+	at := time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)
+
+	// processes snoozing tasks at the same time all get their way
+	errs := make(chan error)
+	for i := 0; i < 10; i++ {
+		id := task.ID(fmt.Sprint(i))
+		go func() {
+			errs <- UpdateState(path, func(s *State) {
+				s.Snoozed[id] = at
+			})
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	state, err := LoadState(path)
+	if err != nil || len(state.Snoozed) != 10 {
+		t.Errorf("got %v, %v", state.Snoozed, err)
+	}
+	if left, _ := filepath.Glob(path + ".*"); len(left) > 0 {
+		t.Errorf("left behind %v", left)
+	}
+
+	// another process holds on to it
+	defer func(wait time.Duration) { lockWait = wait }(lockWait)
+	lockWait = 50 * time.Millisecond
+	if err := os.WriteFile(path+".lock", nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateState(path, func(*State) {}); !errors.Is(err, ErrBusy) {
+		t.Errorf("got %v, want %v", err, ErrBusy)
+	}
+
+	// and is gone without letting go
+	past := time.Now().Add(-2 * staleLock)
+	os.Chtimes(path+".lock", past, past)
+	if err := UpdateState(path, func(*State) {}); err != nil {
+		t.Errorf("lock left behind was not taken over: %v", err)
+	}
+}
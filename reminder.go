@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/td0m/taskman/remind"
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+// reminder sends the reminders of due tasks while the TUI is open. It is
+// configured from the environment:
+//
+//	TASKMAN_NOTIFY  notify-send, bell or a shell command, see remind.NewNotifier
+//
+// Reminders are off in the TUI unless it is set, and `taskman remind` can be
+// run on its own instead.
+type reminder struct {
+	notifier remind.Notifier
+	// state is saved next to the task file
+	state string
+}
+
+type remindDoneMsg struct {
+	alerts []remind.Alert
+	err    error
+}
+
+type remindTickMsg struct{}
+
+const remindInterval = time.Minute
+
+// newReminder returns nil if notifications have not been configured
+func newReminder(store *storage.JSONBackend) *reminder {
+	notify := os.Getenv("TASKMAN_NOTIFY")
+	if notify == "" {
+		return nil
+	}
+	return &reminder{remind.NewNotifier(notify), remindState(store)}
+}
+
+func remindState(store *storage.JSONBackend) string {
+	return store.File() + ".remind"
+}
+
+// check sends the reminders that are due for a snapshot of tasks
+func (r *reminder) check(tasks task.Tasks) tea.Cmd {
+	snapshot := tasks.Clone()
+	return func() tea.Msg {
+		alerts, err := r.send(snapshot, time.Now())
+		return remindDoneMsg{alerts, err}
+	}
+}
+
+// send notifies of the reminders that are due, and records those that were
+// delivered. The state is held meanwhile, so that no other process sends them
+// too. Those that could not be sent go off again at the next check.
+func (r *reminder) send(tasks task.Tasks, now time.Time) ([]remind.Alert, error) {
+	var sent []remind.Alert
+	var notifyErr error
+	err := remind.UpdateState(r.state, func(state *remind.State) {
+		for _, a := range remind.Check(tasks, state, now) {
+			if notifyErr = r.notifier.Notify(a); notifyErr != nil {
+				return
+			}
+			state.Record(a.ID, now)
+			sent = append(sent, a)
+		}
+	})
+	if err != nil {
+		return sent, err
+	}
+	return sent, notifyErr
+}
+
+// next schedules the check after this one
+func (r *reminder) next() tea.Cmd {
+	return tea.Tick(remindInterval, func(time.Time) tea.Msg {
+		return remindTickMsg{}
+	})
+}
+
+// snooze puts off the reminders of a task until the given time
+func snooze(store *storage.JSONBackend, id task.ID, until time.Time) error {
+	return remind.UpdateState(remindState(store), func(state *remind.State) {
+		state.Snoozed[id] = until
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/remind"
+	"github.com/td0m/taskman/task"
+)
+
+// failing delivers its first alert, and fails on the rest
+type failing struct{ sent int }
+
+func (f *failing) Notify(remind.Alert) error {
+	if f.sent > 0 {
+		return errors.New("notify-send: not found")
+	}
+	f.sent++
+	return nil
+}
+
+func TestSend(t *testing.T) {
+	due := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	tasks := task.NewTasks()
+	put(&tasks, "a", "root", task.Task{Due: &due})
+	put(&tasks, "b", "root", task.Task{Due: &due})
+	r := &reminder{&failing{}, filepath.Join(t.TempDir(), "tasks.json.remind")}
+	now := time.Date(2026, 10, 20, 12, 0, 0, 0, time.Local)
+
+	sent, err := r.send(tasks, now)
+	if err == nil || len(sent) != 1 || sent[0].ID != "a" {
+		t.Fatalf("got %v, %v, want only a sent", sent, err)
+	}
+	// b was not delivered, so it goes off again
+	state, err := remind.LoadState(r.state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := state.Reminded["b"]; found || state.Reminded["a"].IsZero() {
+		t.Errorf("got reminded %v, want only a", state.Reminded)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/td0m/taskman/stats"
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+	"github.com/td0m/taskman/ui"
+)
+
+// periods is how many days or weeks a report covers unless told otherwise
+var periods = map[stats.Bucket]int{stats.Day: 14, stats.Week: 8}
+
+var errBadTable = errors.New(`--table is either "periods" or "projects"`)
+
+func reportCommand(store *storage.JSONBackend, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	by := flags.String("by", "week", "length of the periods, day or week")
+	n := flags.Int("periods", 0, "how many periods to cover, 14 days or 8 weeks by default")
+	root := flags.String("root", "root", "only report on the tasks under this one")
+	as := flags.String("format", "text", "text, csv or json")
+	table := flags.String("table", "periods", "what csv lists, periods or projects")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	bucket, err := stats.ParseBucket(*by)
+	if err != nil {
+		return err
+	}
+	if *n <= 0 {
+		*n = periods[bucket]
+	}
+	tasks, err := store.Fetch()
+	if err != nil {
+		return err
+	}
+	if _, found := tasks.Nodes[task.ID(*root)]; !found {
+		return task.ErrBadID
+	}
+	r := stats.Compute(tasks, task.ID(*root), bucket, *n, time.Now())
+
+	switch *as {
+	case "text":
+		_, err := fmt.Fprint(out, renderReport(r, 80))
+		return err
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "csv":
+		return writeCSV(out, r, *table)
+	}
+	return fmt.Errorf("unknown format %q", *as)
+}
+
+// writeCSV writes one of the tables of a report
+func writeCSV(out io.Writer, r stats.Report, table string) error {
+	w := csv.NewWriter(out)
+	switch table {
+	case "periods":
+		w.Write([]string{"start", "created", "completed"})
+		for _, p := range r.Periods {
+			w.Write([]string{p.Start.Format("2006-01-02"), strconv.Itoa(p.Created), strconv.Itoa(p.Completed)})
+		}
+	case "projects":
+		w.Write([]string{"id", "title", "total", "done", "completed", "overdue"})
+		for _, p := range r.Projects {
+			w.Write([]string{string(p.ID), p.Title, strconv.Itoa(p.Total), strconv.Itoa(p.Done), strconv.Itoa(p.Completed), strconv.Itoa(p.Overdue)})
+		}
+	default:
+		return errBadTable
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// renderReport renders a report for the terminal, width columns wide
+func renderReport(r stats.Report, width int) string {
+	var b strings.Builder
+	label := func(s string) string {
+		return ui.RenderSection(runewidth.FillRight(s, 12))
+	}
+	barWidth := clamp(width-12-10, 4, 40)
+
+	fmt.Fprintf(&b, "%s\n\n", ui.RenderSection(r.From.Format("Mon Jan 2")+" – "+r.To.Format("Mon Jan 2")))
+
+	created, completed, most := []int{}, []int{}, 0
+	for _, p := range r.Periods {
+		created = append(created, p.Created)
+		completed = append(completed, p.Completed)
+		most = max(most, p.Completed)
+	}
+	fmt.Fprintf(&b, "%s%s %d\n", label("completed"), ui.Sparkline(completed), r.Completed)
+	fmt.Fprintf(&b, "%s%s %d\n", label("created"), ui.Sparkline(created), r.Created)
+	fmt.Fprintf(&b, "%s%s\n", label("lead time"), leadTime(r.LeadTime))
+	overdue := "nothing was due"
+	if r.Due > 0 {
+		overdue = fmt.Sprintf("%d%% of %d due", int(math.Round(r.OverdueRate*100)), r.Due)
+	}
+	fmt.Fprintf(&b, "%s%s\n", label("overdue"), overdue)
+
+	fmt.Fprintf(&b, "\n%s\n", ui.RenderSection("completed per "+string(r.Bucket)))
+	for _, p := range r.Periods {
+		fmt.Fprintf(&b, "%-12s%s %d\n", p.Start.Format("Mon Jan 2"), ui.Bar(p.Completed, most, barWidth), p.Completed)
+	}
+
+	if len(r.Projects) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", ui.RenderSection("projects"))
+	}
+	for _, p := range r.Projects {
+		line := fmt.Sprintf("%s%s %d/%d", runewidth.FillRight(runewidth.Truncate(p.Title, 11, "…"), 12), ui.Bar(p.Done, p.Total, barWidth), p.Done, p.Total)
+		if p.Completed > 0 {
+			line += fmt.Sprintf("  %d completed", p.Completed)
+		}
+		if p.Overdue > 0 {
+			line += "  " + barLate.Render(fmt.Sprintf("%d overdue", p.Overdue))
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// reportKeys are the keys of the report view, which has no tasks to act on
+var reportKeys = []viewBinding{
+	{binding{[]string{"b"}, "bucket", "switch between days and weeks"}, func(m *app, _ string) error {
+		if m.bucket == stats.Week {
+			m.bucket = stats.Day
+		} else {
+			m.bucket = stats.Week
+		}
+		return nil
+	}},
+	{binding{[]string{"j", "k"}, "scroll", "scroll down or up"}, func(m *app, key string) error {
+		if key == "j" {
+			m.viewport.LineDown(1)
+		} else {
+			m.viewport.LineUp(1)
+		}
+		return nil
+	}},
+}
+
+// leadTime says how long tasks took on average
+func leadTime(days float64) string {
+	switch {
+	case days == 0:
+		return "nothing was completed"
+	case days < 1:
+		return fmt.Sprintf("%.1f hours", days*24)
+	}
+	return fmt.Sprintf("%.1f days", days)
+}
@@ -0,0 +1,104 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+// Point is how many tasks were left open from some time on
+type Point struct {
+	Time      time.Time `json:"time"`
+	Remaining int       `json:"remaining"`
+}
+
+// Burndown is how many of the tasks under a task were left open over time,
+// as told by when each of them was created and done
+type Burndown struct {
+	ID task.ID `json:"id"`
+	// From is when work on the task started, and To the later of now and its
+	// due date
+	From time.Time  `json:"from"`
+	To   time.Time  `json:"to"`
+	Due  *time.Time `json:"due,omitempty"`
+	// Total is how many tasks there are under it now, done or not
+	Total  int     `json:"total"`
+	Points []Point `json:"points"`
+}
+
+// ComputeBurndown reconstructs the burndown of the tasks under id up to now
+func ComputeBurndown(tasks task.Tasks, id task.ID, now time.Time) Burndown {
+	t := tasks.Nodes[id]
+	b := Burndown{ID: id, From: t.Created, To: now, Due: t.Due, Points: []Point{}}
+	if t.Start != nil {
+		b.From = *t.Start
+	}
+
+	type change struct {
+		at time.Time
+		by int
+	}
+	changes := []change{}
+	var walk func(id task.ID)
+	walk = func(id task.ID) {
+		for _, c := range tasks.Children[id] {
+			t := tasks.Nodes[c]
+			b.Total++
+			changes = append(changes, change{t.Created, 1})
+			if t.Done != nil {
+				changes = append(changes, change{*t.Done, -1})
+			}
+			walk(c)
+		}
+	}
+	walk(id)
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].at.Before(changes[j].at) })
+
+	if len(changes) > 0 && (b.From.IsZero() || changes[0].at.Before(b.From)) {
+		b.From = changes[0].at
+	}
+	if b.Due != nil {
+		// due dates are days, which end at midnight
+		if end := b.Due.AddDate(0, 0, 1); end.After(b.To) {
+			b.To = end
+		}
+	}
+
+	remaining := 0
+	for _, c := range changes {
+		remaining += c.by
+		if n := len(b.Points); n > 0 && b.Points[n-1].Time.Equal(c.at) {
+			b.Points[n-1].Remaining = remaining
+			continue
+		}
+		b.Points = append(b.Points, Point{c.at, remaining})
+	}
+	return b
+}
+
+// Remaining is how many tasks were left open at a time
+func (b Burndown) Remaining(at time.Time) int {
+	i := sort.Search(len(b.Points), func(i int) bool { return b.Points[i].Time.After(at) })
+	if i == 0 {
+		return 0
+	}
+	return b.Points[i-1].Remaining
+}
+
+// Ideal is how many tasks should be left at a time to be done by the due
+// date, going down steadily from all of them at the start. It is false
+// without a due date.
+func (b Burndown) Ideal(at time.Time) (float64, bool) {
+	if b.Due == nil {
+		return 0, false
+	}
+	end := b.Due.AddDate(0, 0, 1)
+	switch {
+	case !at.After(b.From):
+		return float64(b.Total), true
+	case !at.Before(end):
+		return 0, true
+	}
+	return float64(b.Total) * float64(end.Sub(at)) / float64(end.Sub(b.From)), true
+}
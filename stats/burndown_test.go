@@ -0,0 +1,53 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+func TestBurndown(t *testing.T) {
+	day := func(d int) *time.Time {
+		t := time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	tasks := task.NewTasks()
+	// three tasks over ten days, due on the 10th
+	put(&tasks, "release", "root", task.Task{Created: *day(1), Due: day(10)})
+	put(&tasks, "notes", "release", task.Task{Created: *day(1), Done: day(3)})
+	put(&tasks, "build", "release", task.Task{Created: *day(2)})
+	put(&tasks, "docs", "build", task.Task{Created: *day(2), Done: day(5)})
+
+	b := ComputeBurndown(tasks, "release", *day(6))
+	if b.Total != 3 || !b.From.Equal(*day(1)) || !b.To.Equal(*day(11)) {
+		t.Fatalf("got %d tasks from %v to %v", b.Total, b.From, b.To)
+	}
+	tests := []struct {
+		at        *time.Time
+		remaining int
+		ideal     float64
+	}{
+		{day(1), 1, 3},
+		{day(2), 3, 2.7},
+		{day(4), 2, 2.1},
+		{day(8), 1, 0.9},
+		{day(12), 1, 0},
+	}
+	for _, tt := range tests {
+		if got := b.Remaining(*tt.at); got != tt.remaining {
+			t.Errorf("%v: %d remaining, want %d", tt.at.Day(), got, tt.remaining)
+		}
+		if got, _ := b.Ideal(*tt.at); got < tt.ideal-0.001 || got > tt.ideal+0.001 {
+			t.Errorf("%v: ideal %v, want %v", tt.at.Day(), got, tt.ideal)
+		}
+	}
+	if got := b.Remaining(*day(1)); got != 1 {
+		t.Errorf("nothing was open before the start, got %d", got)
+	}
+
+	// without a due date, there is no ideal to keep up with
+	if _, ok := ComputeBurndown(tasks, "build", *day(6)).Ideal(*day(4)); ok {
+		t.Error("ideal without a due date")
+	}
+}
@@ -0,0 +1,160 @@
+// Package stats works out how work on the tasks went over a number of days or
+// weeks, for reports and retrospectives.
+package stats
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+// Bucket is the length of the periods a report is split into
+type Bucket string
+
+const (
+	Day  Bucket = "day"
+	Week Bucket = "week"
+)
+
+var ErrBadBucket = errors.New(`periods are either "day" or "week"`)
+
+// ParseBucket reads the length of a period
+func ParseBucket(s string) (Bucket, error) {
+	switch b := Bucket(s); b {
+	case Day, Week:
+		return b, nil
+	}
+	return "", ErrBadBucket
+}
+
+// start returns the start of the period t falls in, in the location of t.
+// Weeks start on monday.
+func (b Bucket) start(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if b == Week {
+		day = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
+
+func (b Bucket) next(t time.Time) time.Time {
+	if b == Week {
+		return t.AddDate(0, 0, 7)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// Period is how many tasks were created and completed in a day or a week
+type Period struct {
+	Start     time.Time `json:"start"`
+	Created   int       `json:"created"`
+	Completed int       `json:"completed"`
+}
+
+// Project is the breakdown of a top level task, and all the tasks under it
+type Project struct {
+	ID    task.ID `json:"id"`
+	Title string  `json:"title"`
+	Total int     `json:"total"`
+	Done  int     `json:"done"`
+	// Completed is how many were done during the report
+	Completed int `json:"completed"`
+	// Overdue is how many are not done past their due date
+	Overdue int `json:"overdue"`
+}
+
+// Report sums up the tasks from the start of the first period until now
+type Report struct {
+	Bucket    Bucket    `json:"bucket"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Periods   []Period  `json:"periods"`
+	Created   int       `json:"created"`
+	Completed int       `json:"completed"`
+	// LeadTime is the average number of days from creating a task to
+	// completing it, over the tasks completed during the report
+	LeadTime float64 `json:"lead_time_days"`
+	// Due is how many tasks were due during the report, and OverdueRate the
+	// share of them that were not done in time
+	Due         int       `json:"due"`
+	OverdueRate float64   `json:"overdue_rate"`
+	Projects    []Project `json:"projects"`
+}
+
+// Compute reports on the tasks under root, over n periods up to now
+func Compute(tasks task.Tasks, root task.ID, bucket Bucket, n int, now time.Time) Report {
+	r := Report{Bucket: bucket, To: now, Projects: []Project{}}
+	start := bucket.start(now)
+	for i := 1; i < n; i++ {
+		start = bucket.start(start.Add(-time.Hour))
+	}
+	r.From = start
+	for s := start; !s.After(now); s = bucket.next(s) {
+		r.Periods = append(r.Periods, Period{Start: s})
+	}
+	period := func(t time.Time) *Period {
+		i := sort.Search(len(r.Periods), func(i int) bool { return r.Periods[i].Start.After(t) }) - 1
+		if i < 0 || t.After(now) {
+			return nil
+		}
+		return &r.Periods[i]
+	}
+
+	late, leads, lead := 0, 0, time.Duration(0)
+	var walk func(id task.ID, p *Project)
+	walk = func(id task.ID, p *Project) {
+		t := tasks.Nodes[id]
+		p.Total++
+		if t.Done != nil {
+			p.Done++
+		}
+		if period := period(t.Created); period != nil {
+			period.Created++
+			r.Created++
+		}
+		if t.Done != nil {
+			if period := period(*t.Done); period != nil {
+				period.Completed++
+				r.Completed++
+				p.Completed++
+				if !t.Created.IsZero() {
+					lead += t.Done.Sub(t.Created)
+					leads++
+				}
+			}
+		}
+		if t.Due != nil {
+			// due dates are days, and a task is only late once its day is over
+			d := t.Due.UTC()
+			deadline := time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, now.Location())
+			overdue := t.Done == nil && deadline.Before(now)
+			if overdue {
+				p.Overdue++
+			}
+			if deadline.After(r.From) && !deadline.After(now) {
+				r.Due++
+				if overdue || t.Done != nil && t.Done.After(deadline) {
+					late++
+				}
+			}
+		}
+		for _, c := range tasks.Children[id] {
+			walk(c, p)
+		}
+	}
+	for _, id := range tasks.Children[root] {
+		p := Project{ID: id, Title: tasks.Nodes[id].Title}
+		walk(id, &p)
+		r.Projects = append(r.Projects, p)
+	}
+
+	if leads > 0 {
+		r.LeadTime = lead.Hours() / 24 / float64(leads)
+	}
+	if r.Due > 0 {
+		r.OverdueRate = float64(late) / float64(r.Due)
+	}
+	return r
+}
@@ -0,0 +1,63 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+// put adds a task with a known ID under parent, titled after the ID unless it
+// has a title of its own
+func put(tasks *task.Tasks, id, parent task.ID, t task.Task) {
+	if t.Title == "" {
+		t.Title = string(id)
+	}
+	tasks.Nodes[id] = t
+	tasks.Children[parent] = append(tasks.Children[parent], id)
+	tasks.Parent[id] = parent
+}
+
+func TestCompute(t *testing.T) {
+	// a wednesday
+	now := time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC)
+	at := func(days, hours int) *time.Time {
+		t := time.Date(2026, 10, 21+days, hours, 0, 0, 0, time.UTC)
+		return &t
+	}
+	tasks := task.NewTasks()
+	put(&tasks, "release", "root", task.Task{Title: "release", Created: *at(-20, 9)})
+	// done in time, in two days
+	put(&tasks, "notes", "release", task.Task{Created: *at(-3, 9), Done: at(-1, 9), Due: at(-1, 0)})
+	// done late, in a day
+	put(&tasks, "build", "release", task.Task{Created: *at(-1, 9), Done: at(0, 9), Due: at(-2, 0)})
+	// not done in time
+	put(&tasks, "docs", "release", task.Task{Created: *at(-9, 9), Due: at(-1, 0)})
+	// due later today, so not late yet
+	put(&tasks, "chores", "root", task.Task{Created: *at(0, 8), Due: at(0, 0)})
+
+	r := Compute(tasks, "root", Day, 3, now)
+	if len(r.Periods) != 3 || !r.From.Equal(*at(-2, 0)) {
+		t.Fatalf("got %d periods from %v", len(r.Periods), r.From)
+	}
+	if got := []int{r.Periods[0].Completed, r.Periods[1].Completed, r.Periods[2].Completed}; got[0] != 0 || got[1] != 1 || got[2] != 1 {
+		t.Errorf("completed per day %v, want [0 1 1]", got)
+	}
+	if r.Created != 2 || r.Completed != 2 {
+		t.Errorf("created %d and completed %d, want 2 and 2", r.Created, r.Completed)
+	}
+	if r.LeadTime != 1.5 {
+		t.Errorf("lead time %v days, want 1.5", r.LeadTime)
+	}
+	if r.Due != 3 || r.OverdueRate != 2.0/3 {
+		t.Errorf("%d due, overdue rate %v, want 3 and 2/3", r.Due, r.OverdueRate)
+	}
+	release := r.Projects[0]
+	if len(r.Projects) != 2 || release.Total != 4 || release.Done != 2 || release.Overdue != 1 || release.Completed != 2 {
+		t.Errorf("projects %+v", r.Projects)
+	}
+
+	if r := Compute(tasks, "root", Week, 2, now); len(r.Periods) != 2 || !r.From.Equal(*at(-9, 0)) || r.Completed != 2 {
+		t.Errorf("weeks from %v: %+v", r.From, r.Periods)
+	}
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Version is the format version written by this taskman.
+// Bump it together with a new entry in migrations whenever the meaning of
+// existing fields changes, or a new field needs a value other than its zero value,
+// or older versions would drop a new field on their next save.
+const Version = 7
+
+// ErrNewerVersion is returned when a file was written by a newer taskman
+var ErrNewerVersion = errors.New("task file was written by a newer version of taskman")
+
+// migration upgrades a raw document by exactly one version
+type migration func(doc map[string]json.RawMessage) error
+
+// migrations[i] upgrades a document from version i to version i+1
+var migrations = []migration{
+	// 0 -> 1: documents are versioned, nothing else changes
+	func(doc map[string]json.RawMessage) error { return nil },
+	// 1 -> 2: tasks have priorities and tags, which start out empty. Older
+	// versions refuse the file rather than drop them.
+	func(doc map[string]json.RawMessage) error { return nil },
+	// 2 -> 3: tasks have dependencies and notes, which start out empty. Older
+	// versions refuse the file rather than drop them.
+	func(doc map[string]json.RawMessage) error { return nil },
+	// 3 -> 4: tasks have reminders, likewise
+	func(doc map[string]json.RawMessage) error { return nil },
+	// 4 -> 5: tasks have statuses, and the tasks a workflow of them, likewise
+	func(doc map[string]json.RawMessage) error { return nil },
+	// 5 -> 6: tasks have start dates, likewise
+	func(doc map[string]json.RawMessage) error { return nil },
+	// 6 -> 7: tasks have saved views, likewise
+	func(doc map[string]json.RawMessage) error { return nil },
+}
+
+// migrate upgrades doc to Version step by step, returning the version it started at
+func migrate(doc map[string]json.RawMessage) (int, error) {
+	from := 0
+	if raw, ok := doc["version"]; ok {
+		if err := json.Unmarshal(raw, &from); err != nil {
+			return from, fmt.Errorf("%w: version: %v", ErrCorrupt, err)
+		}
+	}
+	if from > Version {
+		return from, fmt.Errorf("%w (format %d, this taskman understands up to %d)", ErrNewerVersion, from, Version)
+	}
+	for v := from; v < Version; v++ {
+		if err := migrations[v](doc); err != nil {
+			return from, fmt.Errorf("migrating from format %d to %d: %w", v, v+1, err)
+		}
+	}
+	version, _ := json.Marshal(Version)
+	doc["version"] = version
+	return from, nil
+}
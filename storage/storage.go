@@ -1,15 +1,38 @@
 package storage
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/td0m/taskman/task"
 )
 
+// ErrCorrupt is returned by Fetch when the task file exists but can not be decoded
+var ErrCorrupt = errors.New("corrupt task file")
+
+// document is the persisted form of task.Tasks
+type document struct {
+	Version int `json:"version"`
+	task.Tasks
+}
+
 type JSONBackend struct {
 	file string
+
+	// Hook is set on the tasks returned by Fetch, Restore and Repair
+	Hook task.Hook
+	// After, if set, is called once the changes Hook let through are saved,
+	// and returns what runs the hooks for after them, or nil
+	After func() func() error
 }
 
 func NewJSON(file string) *JSONBackend {
@@ -18,38 +41,266 @@ func NewJSON(file string) *JSONBackend {
 	}
 }
 
+// File returns the path of the main task file
+func (b JSONBackend) File() string {
+	return b.file
+}
+
+// Sync writes tasks to the task file, keeping the previous version as a backup.
+// The new file is written next to the old one and renamed over it, so a failed
+// write never leaves a truncated file behind.
 func (b JSONBackend) Sync(tasks task.Tasks) (task.Tasks, error) {
-	f, err := os.OpenFile(b.file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
+	tmp := b.file + ".tmp"
+	if err := write(tmp, tasks); err != nil {
+		os.Remove(tmp)
 		return tasks, err
 	}
-	defer f.Close()
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "  ")
-	return tasks, enc.Encode(tasks)
+	if _, err := os.Stat(b.file); err == nil {
+		if err := copyFile(b.file, b.file+".bak"); err != nil {
+			return tasks, err
+		}
+	}
+	return tasks, os.Rename(tmp, b.file)
 }
 
+// Fetch reads the task file, creating it if it does not exist yet.
+// Files written in an older format are upgraded, and the original is kept with
+// a ".v<format>.bak" suffix.
 func (b JSONBackend) Fetch() (task.Tasks, error) {
-	f, err := b.open()
+	tasks, err := b.fetch()
+	tasks.Hook = b.Hook
+	return tasks, err
+}
+
+func (b JSONBackend) fetch() (task.Tasks, error) {
+	tasks, version, err := load(b.file)
 	if errors.Is(err, os.ErrNotExist) {
-		f, err := os.Create(b.file)
-		if err != nil {
-			return task.NewTasks(), err
+		tasks = task.NewTasks()
+		return tasks, write(b.file, tasks)
+	}
+	if err != nil || version == Version {
+		return tasks, err
+	}
+	if err := copyFile(b.file, fmt.Sprintf("%s.v%d.bak", b.file, version)); err != nil {
+		return tasks, err
+	}
+	_, err = b.Sync(tasks)
+	return tasks, err
+}
+
+// Emergency writes tasks to a copy next to the task file without touching the
+// original. It is used when the app hits an unexpected failure and the in-memory
+// state can no longer be trusted to overwrite the main file.
+func (b JSONBackend) Emergency(tasks task.Tasks) (string, error) {
+	path := b.file + ".emergency"
+	return path, write(path, tasks)
+}
+
+// Backups lists the backup and emergency copies of the task file, along with
+// the copies kept from before upgrades, newest first
+func (b JSONBackend) Backups() []string {
+	candidates := []string{b.file + ".bak", b.file + ".emergency"}
+	entries, _ := os.ReadDir(filepath.Dir(b.file))
+	base := filepath.Base(b.file)
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, base+".v") && strings.HasSuffix(name, ".bak") {
+			candidates = append(candidates, filepath.Join(filepath.Dir(b.file), name))
 		}
-		defer f.Close()
-		tasks := task.NewTasks()
-		return tasks, json.NewEncoder(f).Encode(tasks)
 	}
+
+	paths := []string{}
+	modified := map[string]time.Time{}
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			paths = append(paths, path)
+			modified[path] = info.ModTime()
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return modified[paths[i]].After(modified[paths[j]])
+	})
+	return paths
+}
+
+// Restore replaces the task file with the contents of a backup.
+// The replaced file is kept with a ".corrupt" suffix.
+func (b JSONBackend) Restore(backup string) (task.Tasks, error) {
+	tasks, _, err := load(backup)
+	if err != nil {
+		return tasks, err
+	}
+	tasks.Hook = b.Hook
+	return tasks, b.replace(tasks)
+}
+
+// Repair salvages whatever can still be decoded from the task file and replaces
+// it with the result. The replaced file is kept with a ".corrupt" suffix. When
+// nothing can be salvaged the file is left alone, for one of the Backups to
+// replace it.
+func (b JSONBackend) Repair() (task.Tasks, error) {
+	data, err := os.ReadFile(b.file)
 	if err != nil {
 		return task.Tasks{}, err
 	}
+	tasks, err := salvage(data)
+	if err != nil {
+		return tasks, fmt.Errorf("%w, restore one of the backups instead", err)
+	}
+	tasks.Hook = b.Hook
+	return tasks, b.replace(tasks)
+}
+
+func (b JSONBackend) replace(tasks task.Tasks) error {
+	if err := copyFile(b.file, b.file+".corrupt"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	_, err := b.Sync(tasks)
+	return err
+}
+
+// load reads and upgrades the document at path, returning the format version it
+// was written in
+func load(path string) (task.Tasks, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return task.Tasks{}, 0, err
+	}
+	corrupt := func(err error) error {
+		return fmt.Errorf("%w: %s: %v", ErrCorrupt, filepath.Base(path), err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return task.Tasks{}, 0, corrupt(err)
+	}
+	version, err := migrate(raw)
+	if err != nil {
+		return task.Tasks{}, version, err
+	}
+	if version != Version {
+		if data, err = json.Marshal(raw); err != nil {
+			return task.Tasks{}, version, err
+		}
+	}
+
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return doc.Tasks, version, corrupt(err)
+	}
+	return doc.Tasks, version, nil
+}
+
+// salvage reads the document one member at a time, dropping the ones that
+// fail instead of giving up on the whole file. A file cut short still gives up
+// everything before the cut, and tasks whose place in the tree was lost end up
+// under root. It fails only when not a single task could be read.
+func salvage(data []byte) (task.Tasks, error) {
+	tasks := task.NewTasks()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	raw := func() (json.RawMessage, error) {
+		var raw json.RawMessage
+		return raw, dec.Decode(&raw)
+	}
+	object := func(member func(key string) error) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok != json.Delim('{') {
+			return fmt.Errorf("%v where an object was expected", tok)
+		}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := member(key.(string)); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	}
+	// each hands over the members of an object one by one, to be kept if they
+	// decode
+	each := func(keep func(id task.ID, data json.RawMessage)) error {
+		return object(func(id string) error {
+			data, err := raw()
+			if err == nil {
+				keep(task.ID(id), data)
+			}
+			return err
+		})
+	}
+
+	err := object(func(key string) error {
+		switch key {
+		case "nodes":
+			return each(func(id task.ID, data json.RawMessage) {
+				var t task.Task
+				if json.Unmarshal(data, &t) == nil {
+					tasks.Nodes[id] = t
+				}
+			})
+		case "children":
+			return each(func(id task.ID, data json.RawMessage) {
+				var children []task.ID
+				if json.Unmarshal(data, &children) == nil {
+					tasks.Children[id] = children
+				}
+			})
+		case "parent":
+			return each(func(id task.ID, data json.RawMessage) {
+				var parent task.ID
+				if json.Unmarshal(data, &parent) == nil {
+					tasks.Parent[id] = parent
+				}
+			})
+		}
+		data, err := raw()
+		switch key {
+		case "statuses":
+			json.Unmarshal(data, &tasks.Statuses)
+		case "views":
+			json.Unmarshal(data, &tasks.Views)
+		}
+		return err
+	})
+	if err != nil && len(tasks.Nodes) <= 1 {
+		return tasks, fmt.Errorf("%w: no task could be salvaged: %v", ErrCorrupt, err)
+	}
+	tasks.Fix()
+	return tasks, nil
+}
+
+func write(path string, tasks task.Tasks) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
 	defer f.Close()
-	var tasks task.Tasks
-	err = json.NewDecoder(f).Decode(&tasks)
-	return tasks, err
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(document{Version, tasks}); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0600)
 }
 
-func (b JSONBackend) open() (*os.File, error) {
-	w, err := os.OpenFile(b.file, os.O_RDWR, 0600)
-	return w, err
+// Hash identifies the tasks as saved, leaving out the views, which only the
+// TUI changes. It tells whether two copies of the task file agree.
+func Hash(tasks task.Tasks) string {
+	tasks.Views = nil
+	data, _ := json.Marshal(tasks)
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
 }
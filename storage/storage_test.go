@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+func TestJSONBackend_FetchMigrates(t *testing.T) {
+	// a file written by each older version
+	legacy := []string{
+		`{"nodes":{"root":{},"a":{"title":"a"}},"children":{"root":["a"]},"parent":{"a":"root"}}`,
+		`{"version":1,"nodes":{"root":{},"a":{"title":"a"}},"children":{"root":["a"]},"parent":{"a":"root"}}`,
+		`{"version":2,"nodes":{"root":{},"a":{"title":"a"}},"children":{"root":["a"]},"parent":{"a":"root"}}`,
+		`{"version":3,"nodes":{"root":{},"a":{"title":"a"}},"children":{"root":["a"]},"parent":{"a":"root"}}`,
+		`{"version":4,"nodes":{"root":{},"a":{"title":"a"}},"children":{"root":["a"]},"parent":{"a":"root"}}`,
+		`{"version":5,"nodes":{"root":{},"a":{"title":"a"}},"children":{"root":["a"]},"parent":{"a":"root"}}`,
+		`{"version":6,"nodes":{"root":{},"a":{"title":"a"}},"children":{"root":["a"]},"parent":{"a":"root"}}`,
+	}
+	if len(legacy) != Version {
+		t.Fatalf("%d legacy files for version %d, add one for the last version", len(legacy), Version)
+	}
+	for from, doc := range legacy {
+		file := filepath.Join(t.TempDir(), "tasks.json")
+		if err := os.WriteFile(file, []byte(doc), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		tasks, err := NewJSON(file).Fetch()
+		if err != nil {
+			t.Fatalf("from %d: %v", from, err)
+		}
+		if tasks.Nodes["a"].Title != "a" {
+			t.Errorf("from %d: lost task while migrating: %+v", from, tasks.Nodes)
+		}
+
+		backup, err := os.ReadFile(fmt.Sprintf("%s.v%d.bak", file, from))
+		if err != nil {
+			t.Fatalf("from %d: no pre-migration backup: %v", from, err)
+		}
+		if string(backup) != doc {
+			t.Errorf("from %d: backup differs from the original:\n%s", from, backup)
+		}
+
+		var saved struct{ Version int }
+		data, _ := os.ReadFile(file)
+		if err := json.Unmarshal(data, &saved); err != nil || saved.Version != Version {
+			t.Errorf("from %d: got version %d, want %d (%v)", from, saved.Version, Version, err)
+		}
+	}
+}
+
+func TestJSONBackend_FetchNewer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tasks.json")
+	newer := `{"version":999,"nodes":{"root":{}}}`
+	if err := os.WriteFile(file, []byte(newer), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewJSON(file).Fetch()
+	if !errors.Is(err, ErrNewerVersion) {
+		t.Errorf("got %v, want %v", err, ErrNewerVersion)
+	}
+	data, _ := os.ReadFile(file)
+	if string(data) != newer {
+		t.Errorf("file from a newer version was modified:\n%s", data)
+	}
+}
+
+func TestJSONBackend_Repair(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tasks.json")
+	// a is fine, b is not a task
+	damaged := `{"version":1,"nodes":{"root":{},"a":{"title":"a"},"b":{"title":7}},"children":{"root":["a"]},"parent":{"a":"root"}}`
+	if err := os.WriteFile(file, []byte(damaged), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := NewJSON(file)
+	if _, err := store.Fetch(); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("got %v, want %v", err, ErrCorrupt)
+	}
+
+	tasks, err := store.Repair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks.Nodes["a"].Title != "a" || len(tasks.Children["root"]) != 1 {
+		t.Errorf("lost a while repairing: %+v", tasks.Nodes)
+	}
+	if _, found := tasks.Nodes["b"]; found {
+		t.Errorf("kept the damaged b")
+	}
+	if kept, _ := os.ReadFile(file + ".corrupt"); string(kept) != damaged {
+		t.Errorf("damaged file not kept:\n%s", kept)
+	}
+	if fetched, err := store.Fetch(); err != nil || fetched.Nodes["a"].Title != "a" {
+		t.Errorf("repaired file does not load: %v", err)
+	}
+}
+
+func TestJSONBackend_RepairTruncated(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tasks.json")
+	// cut short in b, before the tree was written
+	truncated := `{"version":7,"nodes":{"root":{},"a":{"title":"a"},"b":{"ti`
+	if err := os.WriteFile(file, []byte(truncated), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := NewJSON(file).Repair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks.Nodes["a"].Title != "a" || len(tasks.Children["root"]) != 1 || tasks.Parent["a"] != "root" {
+		t.Errorf("lost a while repairing: %+v", tasks)
+	}
+
+	// with nothing to salvage, the file is left for a backup to replace
+	unreadable := `{"version":7,"no`
+	if err := os.WriteFile(file, []byte(unreadable), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewJSON(file).Repair(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("got %v, want %v", err, ErrCorrupt)
+	}
+	if data, _ := os.ReadFile(file); string(data) != unreadable {
+		t.Errorf("file replaced though nothing was salvaged:\n%s", data)
+	}
+}
+
+func TestJSONBackend_Restore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tasks.json")
+	store := NewJSON(file)
+	old := `{"version":1,"nodes":{"root":{},"a":{"title":"old"}},"children":{"root":["a"]},"parent":{"a":"root"}}`
+	if err := os.WriteFile(file+".bak", []byte(old), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// the emergency copy is the newest, so it comes first
+	emergency, err := store.Emergency(task.NewTasks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// so is the copy from before an upgrade, the oldest
+	upgraded := file + ".v1.bak"
+	if err := os.WriteFile(upgraded, []byte(old), 0600); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(file+".bak", past, past)
+	os.Chtimes(upgraded, past.Add(-time.Hour), past.Add(-time.Hour))
+	if got := store.Backups(); len(got) != 3 || got[0] != emergency || got[1] != file+".bak" || got[2] != upgraded {
+		t.Errorf("got backups %v", got)
+	}
+
+	tasks, err := store.Restore(file + ".bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks.Nodes["a"].Title != "old" {
+		t.Errorf("got %+v, want the backup", tasks.Nodes)
+	}
+	if kept, _ := os.ReadFile(file + ".corrupt"); string(kept) != "{" {
+		t.Errorf("damaged file not kept:\n%s", kept)
+	}
+	if fetched, err := store.Fetch(); err != nil || fetched.Nodes["a"].Title != "old" {
+		t.Errorf("restored file does not load: %v", err)
+	}
+}
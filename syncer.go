@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/td0m/taskman/caldav"
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+// syncer runs CalDAV syncs for a store. It is configured from the environment:
+//
+//	TASKMAN_CALDAV_URL        the calendar collection to sync with
+//	TASKMAN_CALDAV_USER       basic auth credentials, if needed
+//	TASKMAN_CALDAV_PASSWORD
+//	TASKMAN_CALDAV_INTERVAL   time between syncs in the TUI, 5m by default
+//	TASKMAN_CALDAV_CONFLICTS  "local" or "remote", the side that wins a conflict
+type syncer struct {
+	client   *caldav.Client
+	interval time.Duration
+	policy   caldav.Policy
+	// state is saved next to the task file
+	state string
+}
+
+type syncDoneMsg struct {
+	res caldav.Result
+	err error
+}
+
+type syncTickMsg struct{}
+
+// newSyncer returns nil if syncing has not been configured
+func newSyncer(store *storage.JSONBackend) (*syncer, error) {
+	url := os.Getenv("TASKMAN_CALDAV_URL")
+	if url == "" {
+		return nil, nil
+	}
+	s := &syncer{
+		client: &caldav.Client{
+			URL:      url,
+			Username: os.Getenv("TASKMAN_CALDAV_USER"),
+			Password: os.Getenv("TASKMAN_CALDAV_PASSWORD"),
+		},
+		interval: 5 * time.Minute,
+		state:    store.File() + ".caldav",
+	}
+	if interval := os.Getenv("TASKMAN_CALDAV_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("TASKMAN_CALDAV_INTERVAL: %w", err)
+		}
+		s.interval = d
+	}
+	switch policy := os.Getenv("TASKMAN_CALDAV_CONFLICTS"); policy {
+	case "", "local":
+	case "remote":
+		s.policy = caldav.KeepRemote
+	default:
+		return nil, fmt.Errorf("TASKMAN_CALDAV_CONFLICTS: expected local or remote, got %q", policy)
+	}
+	return s, nil
+}
+
+// sync syncs a snapshot of tasks in the background
+func (s *syncer) sync(tasks task.Tasks) tea.Cmd {
+	snapshot := tasks.Clone()
+	return func() tea.Msg {
+		state, err := caldav.LoadState(s.state)
+		if err != nil {
+			return syncDoneMsg{err: err}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		res, err := caldav.Sync(ctx, s.client, snapshot, state, s.policy)
+		return syncDoneMsg{res, err}
+	}
+}
+
+// next schedules the sync after this one
+func (s *syncer) next() tea.Cmd {
+	return tea.Tick(s.interval, func(time.Time) tea.Msg {
+		return syncTickMsg{}
+	})
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/td0m/taskman/task"
+)
+
+// tab is the saved view shown, or an empty one when there are none, as in
+// tests
+func (m app) tab() *task.View {
+	if i := m.tabs.Value(); i >= 0 && i < len(m.all.Views) {
+		return &m.all.Views[i]
+	}
+	return &task.View{}
+}
+
+// keep is the filter of the tab, which keeps every task if it cannot be made
+// sense of
+func (m app) keep() predicate {
+	keep, err := parseFilter(m.tab().Filter, time.Now())
+	if err != nil {
+		return func(task.Task) bool { return true }
+	}
+	return keep
+}
+
+// folded reports whether the subtasks of a task are hidden in the tab
+func (m app) folded(id task.ID) bool {
+	return m.all.Folded(*m.tab(), id)
+}
+
+// setFolded folds or unfolds a task in the tab only, forgetting the override
+// once it agrees with the task
+func (m *app) setFolded(id task.ID, folded bool) error {
+	if _, found := m.all.Nodes[id]; !found {
+		return task.ErrBadID
+	}
+	v := m.tab()
+	if folded == m.all.Nodes[id].Folded {
+		delete(v.Folds, id)
+		return nil
+	}
+	if v.Folds == nil {
+		v.Folds = map[task.ID]bool{}
+	}
+	v.Folds[id] = folded
+	return nil
+}
+
+// saveTab keeps how the tasks are arranged and hoisted in the tab, to be
+// saved with the tasks
+func (m *app) saveTab() {
+	v := m.tab()
+	v.Order = orderings[m.arrangement.order].name
+	v.Group = groupings[m.arrangement.group].name
+	v.Flat = m.arrangement.flat
+	v.Root = m.hoisted
+	if v.Order == orderings[0].name {
+		v.Order = ""
+	}
+	if v.Group == groupings[0].name {
+		v.Group = ""
+	}
+}
+
+// loadTab arranges and hoists the tasks the way the tab was left
+func (m *app) loadTab() {
+	v := m.tab()
+	m.arrangement = arrangement{flat: v.Flat}
+	for i, o := range orderings {
+		if o.name == v.Order {
+			m.arrangement.order = i
+		}
+	}
+	for i, g := range groupings {
+		if g.name == v.Group {
+			m.arrangement.group = i
+		}
+	}
+	m.hoisted = v.Root
+	if _, found := m.all.Nodes[m.hoisted]; !found {
+		m.hoisted = ""
+	}
+}
+
+// renameTabs shows the names of the saved views in the tab bar
+func (m *app) renameTabs() {
+	names := make([]string, len(m.all.Views))
+	for i, v := range m.all.Views {
+		names[i] = v.Name
+	}
+	m.tabs.SetTabs(names)
+}
+
+// switchTab leaves the tab the way it is for another one
+func (m *app) switchTab(i int) {
+	m.saveTab()
+	m.tabs.Set(i)
+	m.loadTab()
+}
+
+// showTab switches to another tab, with the cursor where it was left
+func (m *app) showTab(i int) {
+	m.switchTab(i)
+	switch m.view {
+	case boardView:
+		m.board.project = m.project()
+	case burndownView:
+		m.burndown = m.subtree()
+	}
+	m.updateVisible()
+	m.setCursor(max(m.indexOf(m.tab().Cursor), 0))
+}
+
+// findTab returns the tab numbered or named by args
+func (m app) findTab(args string) (int, error) {
+	if n, err := strconv.Atoi(args); err == nil && n >= 1 && n <= len(m.all.Views) {
+		return n - 1, nil
+	}
+	for i, v := range m.all.Views {
+		if strings.EqualFold(v.Name, args) {
+			return i, nil
+		}
+	}
+	for i, v := range m.all.Views {
+		if args != "" && strings.HasPrefix(strings.ToLower(v.Name), strings.ToLower(args)) {
+			return i, nil
+		}
+	}
+	return 0, badArgs("no tab named " + strconv.Quote(args))
+}
+
+// moveTab moves the tab by n places, wrapping around
+func (m *app) moveTab(n int) {
+	i := m.tabs.Value()
+	j := (i + n + len(m.all.Views)) % len(m.all.Views)
+	m.all.MoveView(i, j)
+	m.renameTabs()
+	m.tabs.Set(j)
+	m.updateVisible()
+}
+
+// tabKeys switch to the first nine tabs with alt and their number
+func tabKeys() []action {
+	keys := []action{}
+	for n := 1; n <= 9; n++ {
+		i := n - 1
+		keys = append(keys, action{"tab-" + strconv.Itoa(n), []string{"alt+" + strconv.Itoa(n)}, "show tab " + strconv.Itoa(n), "", func(m *app, _ string) error {
+			if i >= len(m.all.Views) {
+				return nil
+			}
+			m.showTab(i)
+			return nil
+		}})
+	}
+	return keys
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/td0m/taskman/storage"
+	"github.com/td0m/taskman/task"
+)
+
+func TestTabs(t *testing.T) {
+	tasks := task.NewTasks()
+	put(&tasks, "release", "root", task.Task{})
+	put(&tasks, "notes", "release", task.Task{})
+	put(&tasks, "build", "release", task.Task{})
+	put(&tasks, "chores", "root", task.Task{})
+	store := storage.NewJSON(t.TempDir() + "/tasks.json")
+
+	m := newApp(store, tasks)
+	m.height = 20
+	m.updateVisible()
+	if len(m.all.Views) != 3 {
+		t.Fatalf("got %d tabs, want the 3 default ones", len(m.all.Views))
+	}
+
+	// a tab of its own, hoisted, sorted and folded differently
+	m.perform(keymap["alt+1"], "")
+	for _, a := range []struct{ name, args string }{
+		{"tab-new", "Work"},
+		{"hoist", ""},
+		{"sort", "title"},
+		{"tab-left", ""},
+	} {
+		for _, action := range actions {
+			if action.name == a.name {
+				m.perform(action, a.args)
+			}
+		}
+	}
+	m.setCursor(m.indexOf("notes"))
+	m.perform(keymap["alt+2"], "")
+	m.setCursor(m.indexOf("release"))
+	m.perform(keymap["enter"], "")
+
+	if got := m.tabs.Value(); got != 1 || m.tab().Name != "All" || m.root() != "root" {
+		t.Fatalf("got tab %d %q under %s, want All", got, m.tab().Name, m.root())
+	}
+	if m.indexOf("notes") >= 0 {
+		t.Errorf("release should be folded in All")
+	}
+
+	// everything is the way it was left the next time round
+	data, err := store.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = newApp(store, data)
+	m.perform(keymap["alt+1"], "")
+	if v := m.tab(); v.Name != "Work" || m.root() != "release" || v.Order != "title" {
+		t.Errorf("got %+v, want the Work tab hoisted and sorted by title", v)
+	}
+	if got := getID(m.atCursor()); got != "notes" {
+		t.Errorf("cursor on %s, want notes", got)
+	}
+	if got := m.visible; len(got) != 2 || getID(got[0]) != "build" {
+		t.Errorf("got %v, want build then notes", got)
+	}
+	m.perform(keymap["alt+2"], "")
+	if m.indexOf("notes") >= 0 || !m.folded("release") || m.all.Nodes["release"].Folded {
+		t.Errorf("release should be folded in All only")
+	}
+}
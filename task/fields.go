@@ -0,0 +1,63 @@
+package task
+
+// Fields is a set of the parts of a task, such as those a format carries
+type Fields uint
+
+const (
+	FieldTitle Fields = 1 << iota
+	FieldCreated
+	FieldDone
+	FieldDue
+	FieldStart
+	FieldStatus
+	FieldPriority
+	FieldTags
+	FieldDepends
+	FieldNotes
+	FieldReminders
+	FieldFolded
+
+	AllFields = FieldFolded<<1 - 1
+)
+
+// Overlay copies the fields in f from src onto dst, leaving the others as dst
+// has them
+func (f Fields) Overlay(dst, src Task) Task {
+	if f&FieldTitle != 0 {
+		dst.Title = src.Title
+	}
+	if f&FieldCreated != 0 {
+		dst.Created = src.Created
+	}
+	if f&FieldDone != 0 {
+		dst.Done = src.Done
+	}
+	if f&FieldDue != 0 {
+		dst.Due = src.Due
+	}
+	if f&FieldStart != 0 {
+		dst.Start = src.Start
+	}
+	if f&FieldStatus != 0 {
+		dst.Status = src.Status
+	}
+	if f&FieldPriority != 0 {
+		dst.Priority = src.Priority
+	}
+	if f&FieldTags != 0 {
+		dst.Tags = src.Tags
+	}
+	if f&FieldDepends != 0 {
+		dst.Depends = src.Depends
+	}
+	if f&FieldNotes != 0 {
+		dst.Notes = src.Notes
+	}
+	if f&FieldReminders != 0 {
+		dst.Reminders = src.Reminders
+	}
+	if f&FieldFolded != 0 {
+		dst.Folded = src.Folded
+	}
+	return dst
+}
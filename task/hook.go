@@ -0,0 +1,55 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRejected is returned when a hook cancels a change
+var ErrRejected = errors.New("rejected by hook")
+
+// Event is the kind of change a hook is called for
+type Event string
+
+const (
+	EventAdd    Event = "add"
+	EventDone   Event = "done"
+	EventModify Event = "modify"
+	EventMove   Event = "move"
+	EventRemove Event = "remove"
+)
+
+// Change is a change about to be made to a task
+type Change struct {
+	Event Event
+	ID    ID
+	// Parent is the parent of the task once the change is made
+	Parent ID
+	// Before is nil for an added task, and After for a removed one
+	Before *Task
+	After  *Task
+}
+
+// Hook is called before every change made with Add, Move, Remove and the
+// setters, except for SetFolded. It returns the task to store in place of
+// After, or nil to keep it, and an error to cancel the change.
+type Hook func(Change) (*Task, error)
+
+// change runs the hook, if any, and returns the task to store
+func (t Tasks) change(c Change) (Task, error) {
+	after := Task{}
+	if c.After != nil {
+		after = *c.After
+	}
+	if t.Hook == nil {
+		return after, nil
+	}
+	replaced, err := t.Hook(c)
+	if err != nil {
+		return after, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	if replaced != nil {
+		after = *replaced
+	}
+	return after, nil
+}
@@ -0,0 +1,54 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTasks_Hook(t *testing.T) {
+	tasks := tree()
+	events := []Event{}
+	tasks.Hook = func(c Change) (*Task, error) {
+		events = append(events, c.Event)
+		switch {
+		case c.Event == EventRemove && c.ID == "b":
+			return nil, errors.New("b stays")
+		case c.Event == EventMove && c.Parent == "a":
+			tagged := *c.After
+			tagged.Tags = append(tagged.Tags, "from-a")
+			return &tagged, nil
+		}
+		return nil, nil
+	}
+
+	if err := tasks.Remove("b"); !errors.Is(err, ErrRejected) {
+		t.Errorf("got %v, want ErrRejected", err)
+	}
+	if _, found := tasks.Nodes["b"]; !found {
+		t.Errorf("rejected remove went through")
+	}
+	if err := tasks.Move("b", "a", "", Below); err != nil {
+		t.Fatal(err)
+	}
+	if tags := tasks.Nodes["b"].Tags; len(tags) != 1 || tags[0] != "from-a" {
+		t.Errorf("hook did not change the moved task: %v", tags)
+	}
+	now := time.Now()
+	if err := tasks.SetDone("a", &now); err != nil {
+		t.Fatal(err)
+	}
+	if tasks.Nodes["b"].Done == nil {
+		t.Errorf("subtask was not marked done")
+	}
+
+	want := []Event{EventRemove, EventMove, EventDone}
+	if len(events) != len(want) {
+		t.Fatalf("got events %v, want %v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("got events %v, want %v", events, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package task
+
+// Merge copies the tasks of src into t, placing the top level tasks of src at
+// the end of parent. Tasks that already exist in t, matched by ID, have the
+// fields src carries replaced, and are only moved when src nests them under
+// another task.
+// The hook is called for every task, as added or modified.
+// It returns how many tasks were added and how many were updated.
+func (t *Tasks) Merge(src Tasks, parent ID, fields Fields) (added, updated int, err error) {
+	if _, found := t.Nodes[parent]; !found {
+		return 0, 0, ErrNoParent
+	}
+	var walk func(id, into ID) error
+	walk = func(id, into ID) error {
+		for _, c := range src.Children[id] {
+			if c == "root" {
+				continue
+			}
+			before, exists := t.Nodes[c]
+			after := src.Nodes[c]
+			var err error
+			if exists {
+				after = fields.Overlay(before, after)
+				after, err = t.change(Change{EventModify, c, t.Parent[c], &before, &after})
+			} else {
+				after, err = t.change(Change{EventAdd, c, into, nil, &after})
+			}
+			if err != nil {
+				return err
+			}
+			t.Nodes[c] = after
+
+			switch {
+			case !exists:
+				added++
+				err = t.move(c, into, "", Below)
+			case id != "root":
+				updated++
+				err = t.Move(c, into, "", Below)
+			default:
+				updated++
+			}
+			if err != nil {
+				return err
+			}
+			if err := walk(c, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return added, updated, walk("root", parent)
+}
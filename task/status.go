@@ -0,0 +1,110 @@
+package task
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultWorkflow is the workflow of tasks that have not been given one
+var DefaultWorkflow = []string{"todo", "in progress", "review", "done"}
+
+var (
+	ErrBadStatus   = errors.New("status is not part of the workflow")
+	ErrBadWorkflow = errors.New("a workflow needs at least two distinct, non-empty statuses")
+)
+
+// Workflow returns the statuses tasks go through, in order. The first is
+// where new tasks start, and the last is the one of done tasks.
+func (tasks Tasks) Workflow() []string {
+	if len(tasks.Statuses) < 2 {
+		return DefaultWorkflow
+	}
+	return tasks.Statuses
+}
+
+// SetWorkflow replaces the statuses tasks go through. Tasks left with a
+// status that is gone are back at the first one.
+func (tasks *Tasks) SetWorkflow(statuses []string) error {
+	seen := map[string]bool{}
+	for _, s := range statuses {
+		if s == "" || seen[s] {
+			return ErrBadWorkflow
+		}
+		seen[s] = true
+	}
+	if len(statuses) < 2 {
+		return ErrBadWorkflow
+	}
+	tasks.Statuses = append([]string{}, statuses...)
+	return nil
+}
+
+// Status returns the status of a task. Done decides whether it is in the last
+// one, so that tasks marked done without a status, by imports or older
+// versions of taskman, still are.
+func (tasks Tasks) Status(t Task) string {
+	workflow := tasks.Workflow()
+	if t.Done != nil {
+		return workflow[len(workflow)-1]
+	}
+	for _, s := range workflow[:len(workflow)-1] {
+		if s == t.Status {
+			return s
+		}
+	}
+	return workflow[0]
+}
+
+// SetStatus moves a task to another status of the workflow. Moving it to the
+// last one marks it and its subtasks done, as SetDone does, and moving it out
+// of there marks them not done.
+func (tasks *Tasks) SetStatus(id ID, status string) error {
+	t, found := tasks.Nodes[id]
+	if !found || id == "root" {
+		return ErrBadID
+	}
+	workflow := tasks.Workflow()
+	terminal := status == workflow[len(workflow)-1]
+	known := false
+	for _, s := range workflow {
+		known = known || s == status
+	}
+	if !known {
+		return ErrBadStatus
+	}
+
+	before := t.Done
+	event := EventModify
+	switch {
+	case terminal && t.Done == nil:
+		now := time.Now()
+		t = tasks.withDone(t, &now)
+		event = EventDone
+	case !terminal && t.Done != nil:
+		t = tasks.withDone(t, nil)
+	}
+	t.Status = status
+	if err := tasks.modify(id, event, t); err != nil {
+		return err
+	}
+	if after := tasks.Nodes[id].Done; (before == nil) != (after == nil) {
+		for _, c := range tasks.Children[id] {
+			tasks.setDone(c, after)
+		}
+	}
+	return nil
+}
+
+// withDone marks t done, or not done with nil, keeping its status in step
+func (tasks Tasks) withDone(t Task, done *time.Time) Task {
+	workflow := tasks.Workflow()
+	last := workflow[len(workflow)-1]
+	t.Done = done
+	switch {
+	case done != nil:
+		t.Status = last
+	case t.Status == last:
+		t.Status = ""
+	}
+	return t
+}
@@ -0,0 +1,72 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTasks_SetStatus(t *testing.T) {
+	tasks := tree()
+	status := func(id ID) string { return tasks.Status(tasks.Nodes[id]) }
+
+	if got := status("a"); got != "todo" {
+		t.Errorf("new task: got %q, want todo", got)
+	}
+	if err := tasks.SetStatus("a", "review"); err != nil {
+		t.Fatal(err)
+	}
+	if got := status("a"); got != "review" || tasks.Nodes["a"].Done != nil {
+		t.Errorf("got %q, done %v, want review and not done", got, tasks.Nodes["a"].Done)
+	}
+
+	// the last status is done, along with the subtasks
+	if err := tasks.SetStatus("a", "done"); err != nil {
+		t.Fatal(err)
+	}
+	if tasks.Nodes["a"].Done == nil || tasks.Nodes["c"].Done == nil || status("c") != "done" {
+		t.Errorf("a and c should be done: %+v", tasks.Nodes)
+	}
+	if err := tasks.SetStatus("a", "in progress"); err != nil {
+		t.Fatal(err)
+	}
+	if tasks.Nodes["a"].Done != nil || status("c") != "todo" {
+		t.Errorf("a and c should be back to work: %+v", tasks.Nodes)
+	}
+
+	// and the other way round
+	now := time.Now()
+	if err := tasks.SetDone("b", &now); err != nil {
+		t.Fatal(err)
+	}
+	if got := status("b"); got != "done" {
+		t.Errorf("done task: got %q, want done", got)
+	}
+	if err := tasks.SetDone("b", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := status("b"); got != "todo" {
+		t.Errorf("undone task: got %q, want todo", got)
+	}
+
+	if err := tasks.SetStatus("b", "blocked"); !errors.Is(err, ErrBadStatus) {
+		t.Errorf("got %v, want ErrBadStatus", err)
+	}
+}
+
+func TestTasks_SetWorkflow(t *testing.T) {
+	tasks := tree()
+	tasks.SetStatus("a", "review")
+	for _, bad := range [][]string{nil, {"todo"}, {"todo", "todo"}, {"todo", ""}} {
+		if err := tasks.SetWorkflow(bad); !errors.Is(err, ErrBadWorkflow) {
+			t.Errorf("%q: got %v, want ErrBadWorkflow", bad, err)
+		}
+	}
+	if err := tasks.SetWorkflow([]string{"backlog", "doing", "shipped"}); err != nil {
+		t.Fatal(err)
+	}
+	// review is gone, so a starts over
+	if got := tasks.Status(tasks.Nodes["a"]); got != "backlog" {
+		t.Errorf("got %q, want backlog", got)
+	}
+}
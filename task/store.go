@@ -16,6 +16,7 @@ const (
 var (
 	ErrNoParent = errors.New("invalid parent ID")
 	ErrBadID    = errors.New("invalid ID")
+	ErrCycle    = errors.New("task can not be moved under itself")
 )
 
 func init() {
@@ -24,7 +25,8 @@ func init() {
 
 const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
-func randomID() ID {
+// NewID returns a random ID for a new task
+func NewID() ID {
 	b := make([]byte, 8)
 	for i := range b {
 		b[i] = letters[rand.Intn(len(letters))]
@@ -32,14 +34,44 @@ func randomID() ID {
 	return ID(b)
 }
 
-func (t *Tasks) Add(parent ID, anchor ID, pos Pos) error {
-	id := randomID()
-	t.Nodes[id] = newTask()
-	t.Move(id, parent, anchor, pos)
-	return nil
+// Add creates an empty task under parent, next to anchor, and returns its ID
+func (t *Tasks) Add(parent ID, anchor ID, pos Pos) (ID, error) {
+	if _, found := t.Nodes[parent]; !found {
+		return "", ErrNoParent
+	}
+	id := NewID()
+	created := newTask()
+	added, err := t.change(Change{EventAdd, id, parent, nil, &created})
+	if err != nil {
+		return "", err
+	}
+	t.Nodes[id] = added
+	return id, t.move(id, parent, anchor, pos)
+}
+
+// Move places target under parent, next to anchor.
+// Moving a task under itself or any of its descendants fails with ErrCycle.
+func (t *Tasks) Move(target ID, parent ID, anchor ID, pos Pos) error {
+	if _, found := t.Nodes[target]; !found || target == "root" {
+		return ErrBadID
+	}
+	if _, found := t.Nodes[parent]; !found {
+		return ErrNoParent
+	}
+	if t.isAncestor(target, parent) {
+		return ErrCycle
+	}
+	before, after := t.Nodes[target], t.Nodes[target]
+	moved, err := t.change(Change{EventMove, target, parent, &before, &after})
+	if err != nil {
+		return err
+	}
+	t.Nodes[target] = moved
+	return t.move(target, parent, anchor, pos)
 }
 
-func (t *Tasks) Move(target ID, parent ID, anchor ID, pos Pos) {
+// move places target without any checks
+func (t *Tasks) move(target ID, parent ID, anchor ID, pos Pos) error {
 	// delete from current parent
 	if parent, ok := t.Parent[target]; ok {
 		t.removeChild(parent, target)
@@ -51,11 +83,29 @@ func (t *Tasks) Move(target ID, parent ID, anchor ID, pos Pos) {
 	for i, c := range children {
 		if c == anchor {
 			t.Children[parent] = insert(children, i+int(pos), target)
-			return
+			return nil
 		}
 	}
 	// cursor not found but still insert
 	t.Children[parent] = append(children, target)
+	return nil
+}
+
+// isAncestor reports whether ancestor is id itself or one of its parents
+func (t Tasks) isAncestor(ancestor, id ID) bool {
+	seen := map[ID]bool{}
+	for !seen[id] {
+		if id == ancestor {
+			return true
+		}
+		seen[id] = true
+		parent, ok := t.Parent[id]
+		if !ok {
+			return false
+		}
+		id = parent
+	}
+	return false
 }
 
 func (t *Tasks) removeChild(parent, child ID) {
@@ -77,34 +127,80 @@ func (tasks Tasks) SetTitle(id ID, title string) error {
 		return ErrBadID
 	}
 	t.Title = title
-	tasks.Nodes[id] = t
-	return nil
+	return tasks.modify(id, EventModify, t)
 }
 
+// SetDone marks a task and all of its subtasks done, or not done with nil.
+// The hook is only called for the task itself.
 func (tasks *Tasks) SetDone(id ID, done *time.Time) error {
 	t, found := tasks.Nodes[id]
 	if !found {
 		return ErrBadID
 	}
-	t.Done = done
-
+	t = tasks.withDone(t, done)
+	event := EventDone
+	if done == nil {
+		event = EventModify
+	}
+	if err := tasks.modify(id, event, t); err != nil {
+		return err
+	}
 	for _, c := range tasks.Children[id] {
-		err := tasks.SetDone(c, done)
-		if err != nil {
-			return err
-		}
+		tasks.setDone(c, done)
 	}
-	tasks.Nodes[id] = t
 	return nil
 }
 
+func (tasks *Tasks) setDone(id ID, done *time.Time) {
+	tasks.Nodes[id] = tasks.withDone(tasks.Nodes[id], done)
+	for _, c := range tasks.Children[id] {
+		tasks.setDone(c, done)
+	}
+}
+
 func (tasks Tasks) SetDue(id ID, due *time.Time) error {
 	t, found := tasks.Nodes[id]
 	if !found {
 		return ErrBadID
 	}
 	t.Due = due
-	tasks.Nodes[id] = t
+	return tasks.modify(id, EventModify, t)
+}
+
+func (tasks Tasks) SetStart(id ID, start *time.Time) error {
+	t, found := tasks.Nodes[id]
+	if !found {
+		return ErrBadID
+	}
+	t.Start = start
+	return tasks.modify(id, EventModify, t)
+}
+
+func (tasks Tasks) SetReminders(id ID, reminders []string) error {
+	t, found := tasks.Nodes[id]
+	if !found {
+		return ErrBadID
+	}
+	t.Reminders = reminders
+	return tasks.modify(id, EventModify, t)
+}
+
+// Set replaces a task with t, for changes to more than one field at once
+func (tasks Tasks) Set(id ID, t Task) error {
+	if _, found := tasks.Nodes[id]; !found || id == "root" {
+		return ErrBadID
+	}
+	return tasks.modify(id, EventModify, t)
+}
+
+// modify stores the changed task t, once the hook agrees
+func (tasks Tasks) modify(id ID, event Event, t Task) error {
+	before := tasks.Nodes[id]
+	after, err := tasks.change(Change{event, id, tasks.Parent[id], &before, &t})
+	if err != nil {
+		return err
+	}
+	tasks.Nodes[id] = after
 	return nil
 }
 
@@ -121,19 +217,33 @@ func (tasks Tasks) SetFolded(id ID, folded bool) error {
 	return nil
 }
 
+// Remove deletes a task and all of its subtasks.
+// The hook is only called for the task itself.
 func (tasks *Tasks) Remove(id ID) error {
+	before, found := tasks.Nodes[id]
+	if !found || id == "root" {
+		return ErrBadID
+	}
+	if _, err := tasks.change(Change{EventRemove, id, tasks.Parent[id], &before, nil}); err != nil {
+		return err
+	}
+	tasks.remove(id)
+	return nil
+}
+
+func (tasks *Tasks) remove(id ID) {
 	// delete itself
 	delete(tasks.Nodes, id)
 
-	// delete all children recursively
-	for _, c := range tasks.Children[id] {
-		tasks.Remove(c)
+	// delete all children recursively, iterating over a copy as each child
+	// removes itself from the list
+	for _, c := range append([]ID{}, tasks.Children[id]...) {
+		tasks.remove(c)
 	}
 	delete(tasks.Children, id)
 
 	// delete from parent
 	tasks.removeChild(tasks.Parent[id], id)
-	return nil
 }
 
 func insert(a []ID, index int, value ID) []ID {
@@ -8,6 +8,13 @@ type Tasks struct {
 	Nodes    map[ID]Task `json:"nodes"`
 	Children map[ID][]ID `json:"children"`
 	Parent   map[ID]ID   `json:"parent"`
+	// Statuses is the workflow of the tasks, see Workflow
+	Statuses []string `json:"statuses,omitempty"`
+	// Views are the tabs of the tree, see View
+	Views []View `json:"views,omitempty"`
+
+	// Hook, if set, is called before each change
+	Hook Hook `json:"-"`
 }
 
 func NewTasks() Tasks {
@@ -18,15 +25,58 @@ func NewTasks() Tasks {
 	}
 }
 
+// Clone returns a deep copy of t, which is safe to hand over to another goroutine
+func (t Tasks) Clone() Tasks {
+	c := Tasks{
+		Nodes:    make(map[ID]Task, len(t.Nodes)),
+		Children: make(map[ID][]ID, len(t.Children)),
+		Parent:   make(map[ID]ID, len(t.Parent)),
+		Statuses: append([]string(nil), t.Statuses...),
+		Hook:     t.Hook,
+	}
+	for _, v := range t.Views {
+		c.Views = append(c.Views, v.clone())
+	}
+	for id, task := range t.Nodes {
+		c.Nodes[id] = task
+	}
+	for id, children := range t.Children {
+		c.Children[id] = append([]ID{}, children...)
+	}
+	for id, parent := range t.Parent {
+		c.Parent[id] = parent
+	}
+	return c
+}
+
 type Task struct {
 	Title   string     `json:"title,omitempty"`
 	Created time.Time  `json:"created,omitempty"`
 	Done    *time.Time `json:"done,omitempty"`
 	Due     *time.Time `json:"due,omitempty"`
+	// Start is when work on the task is planned to begin, a day like Due
+	Start *time.Time `json:"start,omitempty"`
+	// Status is where the task is in the workflow, see Tasks.Status
+	Status string `json:"status,omitempty"`
+
+	// Priority is a single letter from A (highest) to Z, or empty for none
+	Priority string   `json:"priority,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	// Depends lists the tasks that have to be done before this one
+	Depends []ID   `json:"depends,omitempty"`
+	Notes   []Note `json:"notes,omitempty"`
+	// Reminders are offsets from Due, such as "1h before" or "morning of"
+	Reminders []string `json:"reminders,omitempty"`
 
 	Folded bool `json:"folded,omitempty"`
 }
 
+// Note is a comment added to a task
+type Note struct {
+	Created time.Time `json:"created"`
+	Text    string    `json:"text"`
+}
+
 func newTask() Task {
 	return Task{
 		Created: time.Now(),
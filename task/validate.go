@@ -0,0 +1,235 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type ProblemKind int
+
+const (
+	MissingRoot ProblemKind = iota
+	Orphan
+	Cycle
+	DanglingChild
+	ParentMismatch
+	DuplicateChild
+)
+
+func (k ProblemKind) String() string {
+	return [...]string{
+		"missing root",
+		"orphan",
+		"cycle",
+		"dangling child",
+		"parent mismatch",
+		"duplicate child",
+	}[k]
+}
+
+// Problem is a single inconsistency between Nodes, Children and Parent
+type Problem struct {
+	Kind ProblemKind
+	ID   ID
+	// Parent is the list or parent entry the problem was found in, if any
+	Parent ID
+}
+
+func (p Problem) String() string {
+	switch p.Kind {
+	case MissingRoot:
+		return "root task is missing"
+	case Orphan:
+		return fmt.Sprintf("%s: can not be reached from root", p.ID)
+	case Cycle:
+		return fmt.Sprintf("%s: is its own ancestor", p.ID)
+	case DanglingChild:
+		return fmt.Sprintf("%s: listed as a child of %s but does not exist", p.ID, p.Parent)
+	case ParentMismatch:
+		return fmt.Sprintf("%s: parent is recorded as %q but it is not listed there", p.ID, p.Parent)
+	case DuplicateChild:
+		return fmt.Sprintf("%s: listed more than once (again under %s)", p.ID, p.Parent)
+	}
+	return p.Kind.String()
+}
+
+// Problems is returned by Validate when the task graph is inconsistent
+type Problems []Problem
+
+func (p Problems) Error() string {
+	lines := make([]string, len(p))
+	for i, problem := range p {
+		lines[i] = problem.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks that Children and Parent describe the same tree, rooted at
+// "root" and covering every node. It returns Problems if they do not.
+func (t Tasks) Validate() error {
+	problems := Problems{}
+	if _, found := t.Nodes["root"]; !found {
+		problems = append(problems, Problem{Kind: MissingRoot})
+	}
+
+	listed := map[ID]ID{}
+	for _, parent := range sortedKeys(t.Children) {
+		for _, c := range t.Children[parent] {
+			if _, found := t.Nodes[c]; !found {
+				problems = append(problems, Problem{Kind: DanglingChild, ID: c, Parent: parent})
+				continue
+			}
+			if _, dup := listed[c]; dup {
+				problems = append(problems, Problem{Kind: DuplicateChild, ID: c, Parent: parent})
+				continue
+			}
+			listed[c] = parent
+			if t.Parent[c] != parent {
+				problems = append(problems, Problem{Kind: ParentMismatch, ID: c, Parent: t.Parent[c]})
+			}
+		}
+	}
+	for _, id := range sortedKeys(t.Parent) {
+		if _, ok := listed[id]; !ok {
+			problems = append(problems, Problem{Kind: ParentMismatch, ID: id, Parent: t.Parent[id]})
+		}
+	}
+
+	reachable := t.reachable()
+	for _, id := range sortedKeys(t.Nodes) {
+		if id == "root" || reachable[id] {
+			continue
+		}
+		kind := Orphan
+		if t.inCycle(id, listed) {
+			kind = Cycle
+		}
+		problems = append(problems, Problem{Kind: kind, ID: id})
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// Fix rebuilds Children and Parent so that they pass Validate. Dangling and
+// duplicate entries are dropped, and tasks that can not be reached from root are
+// put back under their recorded parent, or at the end of root when that is not
+// possible. It returns the problems that were fixed.
+func (t *Tasks) Fix() Problems {
+	err := t.Validate()
+	if err == nil {
+		return nil
+	}
+	problems := err.(Problems)
+
+	if _, found := t.Nodes["root"]; !found {
+		t.Nodes["root"] = Task{}
+	}
+	old := t.Children
+	t.Children = map[ID][]ID{}
+	seen := map[ID]bool{"root": true}
+
+	var attach func(parent, id ID)
+	attach = func(parent, id ID) {
+		seen[id] = true
+		t.Children[parent] = append(t.Children[parent], id)
+		for _, c := range old[id] {
+			if _, found := t.Nodes[c]; found && !seen[c] {
+				attach(id, c)
+			}
+		}
+	}
+	for _, c := range old["root"] {
+		if _, found := t.Nodes[c]; found && !seen[c] {
+			attach("root", c)
+		}
+	}
+
+	// reattach whatever is left, preferring the recorded parent
+	for {
+		pending := []ID{}
+		for _, id := range sortedKeys(t.Nodes) {
+			if !seen[id] {
+				pending = append(pending, id)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		progress := false
+		for _, id := range pending {
+			if parent, ok := t.Parent[id]; ok && seen[parent] && !seen[id] {
+				attach(parent, id)
+				progress = true
+			}
+		}
+		if !progress {
+			attach("root", pending[0])
+		}
+	}
+
+	t.Parent = map[ID]ID{}
+	for parent, children := range t.Children {
+		for _, c := range children {
+			t.Parent[c] = parent
+		}
+	}
+	return problems
+}
+
+func (t Tasks) reachable() map[ID]bool {
+	seen := map[ID]bool{}
+	var walk func(id ID)
+	walk = func(id ID) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		for _, c := range t.Children[id] {
+			walk(c)
+		}
+	}
+	walk("root")
+	return seen
+}
+
+// inCycle reports whether following the parents of id, as listed in Children,
+// leads back to id
+func (t Tasks) inCycle(id ID, listed map[ID]ID) bool {
+	seen := map[ID]bool{}
+	for current := id; !seen[current]; {
+		seen[current] = true
+		parent, ok := listed[current]
+		if !ok {
+			return false
+		}
+		if parent == id {
+			return true
+		}
+		current = parent
+	}
+	return false
+}
+
+func sortedKeys(m interface{}) []ID {
+	ids := []ID{}
+	switch m := m.(type) {
+	case map[ID]Task:
+		for id := range m {
+			ids = append(ids, id)
+		}
+	case map[ID][]ID:
+		for id := range m {
+			ids = append(ids, id)
+		}
+	case map[ID]ID:
+		for id := range m {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
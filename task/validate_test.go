@@ -0,0 +1,77 @@
+package task
+
+import (
+	"errors"
+	"testing"
+)
+
+func tree() Tasks {
+	t := NewTasks()
+	for _, id := range []ID{"a", "b", "c"} {
+		t.Nodes[id] = Task{Title: string(id)}
+	}
+	t.Children["root"] = []ID{"a", "b"}
+	t.Children["a"] = []ID{"c"}
+	t.Parent["a"] = "root"
+	t.Parent["b"] = "root"
+	t.Parent["c"] = "a"
+	return t
+}
+
+func TestTasks_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(t *Tasks)
+		want   []ProblemKind
+	}{
+		{"valid", func(t *Tasks) {}, nil},
+		{"missing root", func(t *Tasks) { delete(t.Nodes, "root") }, []ProblemKind{MissingRoot}},
+		{"dangling child", func(t *Tasks) { t.Children["b"] = []ID{"x"} }, []ProblemKind{DanglingChild}},
+		{"duplicate child", func(t *Tasks) { t.Children["b"] = []ID{"c"} }, []ProblemKind{DuplicateChild}},
+		{"parent mismatch", func(t *Tasks) { t.Parent["c"] = "b" }, []ProblemKind{ParentMismatch}},
+		{"orphan", func(t *Tasks) { t.Nodes["d"] = Task{} }, []ProblemKind{Orphan}},
+		{"cycle", func(t *Tasks) {
+			t.Children["root"] = []ID{"b"}
+			t.Children["c"] = []ID{"a"}
+			t.Parent["a"] = "c"
+		}, []ProblemKind{Cycle, Cycle}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := tree()
+			tt.mutate(&tasks)
+			err := tasks.Validate()
+			var problems Problems
+			errors.As(err, &problems)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("got %v, want %v", problems, tt.want)
+			}
+			for i, p := range problems {
+				if p.Kind != tt.want[i] {
+					t.Errorf("got %v, want %v", p.Kind, tt.want[i])
+				}
+			}
+
+			tasks.Fix()
+			if err := tasks.Validate(); err != nil {
+				t.Errorf("still invalid after Fix:\n%v", err)
+			}
+		})
+	}
+}
+
+func TestTasks_MoveCycle(t *testing.T) {
+	tasks := tree()
+	if err := tasks.Move("a", "c", "", Below); !errors.Is(err, ErrCycle) {
+		t.Errorf("moving under a descendant: got %v, want %v", err, ErrCycle)
+	}
+	if err := tasks.Move("a", "a", "", Below); !errors.Is(err, ErrCycle) {
+		t.Errorf("moving under itself: got %v, want %v", err, ErrCycle)
+	}
+	if err := tasks.Move("c", "b", "", Below); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := tasks.Validate(); err != nil {
+		t.Errorf("invalid after Move:\n%v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package task
+
+import "errors"
+
+var ErrLastView = errors.New("the last view cannot be removed")
+
+// View is a tab of the tree, saved along with the tasks so that it is the way
+// it was left the next time
+type View struct {
+	Name string `json:"name"`
+	// Filter picks the tasks the view shows, see the filter action
+	Filter string `json:"filter,omitempty"`
+	// Order and Group name how the tasks are sorted and grouped
+	Order string `json:"order,omitempty"`
+	Group string `json:"group,omitempty"`
+	Flat  bool   `json:"flat,omitempty"`
+	// Folds override whether tasks are folded, in this view only
+	Folds map[ID]bool `json:"folds,omitempty"`
+	// Root is the hoisted task, Cursor the one last under the cursor
+	Root   ID `json:"root,omitempty"`
+	Cursor ID `json:"cursor,omitempty"`
+}
+
+// DefaultViews are the views of tasks that have none saved yet
+func DefaultViews() []View {
+	return []View{
+		{Name: "All", Filter: "recent:5"},
+		{Name: "Inbox", Filter: "undated"},
+		{Name: "Today", Filter: "due:today recent:1"},
+	}
+}
+
+// Folded reports whether a task is folded in a view, as the task is unless
+// the view says otherwise
+func (t Tasks) Folded(v View, id ID) bool {
+	if folded, found := v.Folds[id]; found {
+		return folded
+	}
+	return t.Nodes[id].Folded
+}
+
+// clone copies v, folds and all
+func (v View) clone() View {
+	folds := v.Folds
+	if folds != nil {
+		v.Folds = make(map[ID]bool, len(folds))
+		for id, folded := range folds {
+			v.Folds[id] = folded
+		}
+	}
+	return v
+}
+
+// AddView adds a view at i, a copy of the one before it if there is any
+func (t *Tasks) AddView(i int, name string) {
+	v := View{Name: name}
+	if i > 0 && i <= len(t.Views) {
+		v = t.Views[i-1].clone()
+		v.Name = name
+	}
+	t.Views = append(t.Views[:i], append([]View{v}, t.Views[i:]...)...)
+}
+
+// RemoveView removes the view at i, as long as it is not the last one
+func (t *Tasks) RemoveView(i int) error {
+	if len(t.Views) < 2 {
+		return ErrLastView
+	}
+	t.Views = append(t.Views[:i], t.Views[i+1:]...)
+	return nil
+}
+
+// MoveView moves the view at i to j
+func (t *Tasks) MoveView(i, j int) {
+	v := t.Views[i]
+	t.Views = append(t.Views[:i], t.Views[i+1:]...)
+	t.Views = append(t.Views[:j], append([]View{v}, t.Views[j:]...)...)
+}
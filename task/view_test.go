@@ -0,0 +1,50 @@
+package task
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTasks_Views(t *testing.T) {
+	tasks := tree()
+	tasks.Views = DefaultViews()
+	names := func() string {
+		s := ""
+		for _, v := range tasks.Views {
+			s += v.Name + " "
+		}
+		return s
+	}
+
+	tasks.Views[0].Folds = map[ID]bool{"a": true}
+	tasks.AddView(1, "Work")
+	if got := names(); got != "All Work Inbox Today " {
+		t.Errorf("got %s", got)
+	}
+	// a new view starts as a copy of the one before it
+	if v := tasks.Views[1]; v.Filter != "recent:5" || !tasks.Folded(v, "a") {
+		t.Errorf("got %+v, want a copy of All", v)
+	}
+	tasks.Views[1].Folds["a"] = false
+	if !tasks.Folded(tasks.Views[0], "a") {
+		t.Errorf("folding in a copy should leave the original alone")
+	}
+
+	tasks.MoveView(1, 3)
+	if got := names(); got != "All Inbox Today Work " {
+		t.Errorf("got %s", got)
+	}
+	tasks.MoveView(3, 0)
+	if got := names(); got != "Work All Inbox Today " {
+		t.Errorf("got %s", got)
+	}
+
+	for len(tasks.Views) > 1 {
+		if err := tasks.RemoveView(0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tasks.RemoveView(0); !errors.Is(err, ErrLastView) {
+		t.Errorf("got %v, want ErrLastView", err)
+	}
+}
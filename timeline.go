@@ -0,0 +1,249 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	"github.com/td0m/taskman/task"
+	"github.com/td0m/taskman/ui"
+)
+
+// timeline is the state of the timeline view, which draws the tasks of the
+// tree as bars from their start to their due date
+type timeline struct {
+	zoom int
+	// from is the first day shown, zero until the view is first opened
+	from time.Time
+}
+
+// zoom is how much time the timeline shows at once
+type zoom struct {
+	name string
+	// perDay is how many columns a day takes
+	perDay float64
+	// label names the day a column starts on, if it is worth a label
+	label func(day time.Time) string
+}
+
+var zooms = []zoom{
+	{"day", 3, func(day time.Time) string {
+		return strconv.Itoa(day.Day())
+	}},
+	{"week", 1, func(day time.Time) string {
+		if day.Weekday() != time.Monday {
+			return ""
+		}
+		return day.Format("Jan 2")
+	}},
+	{"month", 0.25, func(day time.Time) string {
+		switch {
+		case day.Day() != 1:
+			return ""
+		case day.Month() == time.January:
+			return day.Format("2006")
+		}
+		return day.Format("Jan")
+	}},
+}
+
+var (
+	bar        = lipgloss.NewStyle().Foreground(ui.Secondary)
+	barDone    = lipgloss.NewStyle().Foreground(ui.Faded)
+	barLate    = lipgloss.NewStyle().Foreground(ui.Red)
+	todayStyle = lipgloss.NewStyle().Foreground(ui.Yellow)
+)
+
+// labelWidth is how wide the tree on the left of the bars is
+func (m app) labelWidth() int {
+	return clamp(m.viewport.Width/3, 20, 40)
+}
+
+// days is how many days fit next to the tree
+func (m app) days() int {
+	return int(float64(m.viewport.Width-m.labelWidth()) / zooms[m.timeline.zoom].perDay)
+}
+
+// column returns where a day is drawn, which may be off screen
+func (m app) column(day time.Time) int {
+	days := dayOf(day).Sub(m.timeline.from).Hours() / 24
+	return int(math.Floor(days * zooms[m.timeline.zoom].perDay))
+}
+
+// scrollTimeline shows today, a quarter of the way in
+func (m *app) scrollTimeline() {
+	today := dayOf(time.Now().Truncate(time.Hour * 24))
+	m.timeline.from = today.AddDate(0, 0, -m.days()/4)
+}
+
+// timelineKeys are the keys that differ in the timeline view
+var timelineKeys = []viewBinding{
+	{binding{[]string{"h", "l"}, "scroll", "show earlier or later days"}, func(m *app, key string) error {
+		by := max(m.days()/4, 1)
+		if key == "h" {
+			by = -by
+		}
+		m.timeline.from = m.timeline.from.AddDate(0, 0, by)
+		return nil
+	}},
+	{binding{[]string{"+", "-"}, "zoom", "zoom in or out"}, func(m *app, key string) error {
+		// zooming keeps the middle where it is
+		middle := m.timeline.from.AddDate(0, 0, m.days()/2)
+		if key == "+" {
+			m.timeline.zoom = max(m.timeline.zoom-1, 0)
+		} else {
+			m.timeline.zoom = min(m.timeline.zoom+1, len(zooms)-1)
+		}
+		m.timeline.from = middle.AddDate(0, 0, -m.days()/2)
+		return nil
+	}},
+	{binding{[]string{"."}, "today", "scroll back to today"}, func(m *app, _ string) error {
+		m.scrollTimeline()
+		return nil
+	}},
+	{binding{[]string{"H", "L"}, "shift", "move the dates of the task by a day"}, func(m *app, key string) error {
+		by := -1
+		if key == "L" {
+			by = 1
+		}
+		id := getID(m.atCursor())
+		if len(id) == 0 {
+			return nil
+		}
+		err := m.moveDates(id, by)
+		m.updateVisible()
+		return err
+	}},
+}
+
+// deadline is the due date of the closest ancestor that has one, which the
+// task had better not run past
+func (m app) deadline(p path) *time.Time {
+	for i := len(p) - 2; i >= 0; i-- {
+		if due := m.all.Nodes[p[i]].Due; due != nil {
+			return due
+		}
+	}
+	return nil
+}
+
+// renderScale renders the dates above the bars
+func (m app) renderScale() string {
+	width := m.viewport.Width - m.labelWidth()
+	today := m.column(time.Now().Truncate(time.Hour * 24))
+	z := zooms[m.timeline.zoom]
+
+	line := []rune(strings.Repeat(" ", width))
+	for day, end := m.timeline.from, 0; m.column(day) < width; day = day.AddDate(0, 0, 1) {
+		label, x := z.label(day), m.column(day)
+		// labels never overlap
+		if label == "" || x < end || x+len(label) > width {
+			continue
+		}
+		copy(line[x:], []rune(label))
+		end = x + len(label) + 1
+	}
+	s := string(line)
+	if today >= 0 && today < width {
+		// today is marked, without hiding the label it falls on
+		mark := "▼"
+		if line[today] != ' ' {
+			mark = string(line[today])
+		}
+		s = string(line[:today]) + todayStyle.Render(mark) + string(line[today+1:])
+	}
+	return ui.RenderSection(runewidth.FillRight(z.name, m.labelWidth())) + s
+}
+
+// renderBar renders the bar of a task between its start and due dates, red
+// where it runs past the due date of its parents
+func (m app) renderBar(p path) string {
+	width := m.viewport.Width - m.labelWidth()
+	t := m.all.Nodes[getID(p)]
+	cells := make([]string, width)
+	for i := range cells {
+		cells[i] = " "
+	}
+	if today := m.column(time.Now().Truncate(time.Hour * 24)); today >= 0 && today < width {
+		cells[today] = todayStyle.Render("│")
+	}
+
+	start, due := t.Start, t.Due
+	if start == nil {
+		start = due
+	}
+	if due == nil {
+		due = start
+	}
+	if start == nil {
+		return strings.Join(cells, "")
+	}
+	if due.Before(*start) {
+		start, due = due, start
+	}
+
+	from, to := m.column(*start), max(m.column(due.AddDate(0, 0, 1))-1, m.column(*start))
+	late := width
+	if deadline := m.deadline(p); deadline != nil {
+		late = m.column(deadline.AddDate(0, 0, 1))
+	}
+	style := bar
+	if t.Done != nil {
+		style = barDone
+	}
+	switch {
+	case to < 0:
+		cells[0] = style.Render("◂")
+	case from >= width:
+		cells[width-1] = style.Render("▸")
+	default:
+		for x := max(from, 0); x <= min(to, width-1); x++ {
+			if x >= late && t.Done == nil {
+				cells[x] = barLate.Render("█")
+			} else {
+				cells[x] = style.Render("█")
+			}
+		}
+	}
+	return strings.Join(cells, "")
+}
+
+func (m app) renderTimeline() string {
+	labelWidth := m.labelWidth()
+	if m.viewport.Width-labelWidth < 1 {
+		return ""
+	}
+	s := ""
+	for i, p := range m.visible {
+		t := m.all.Nodes[getID(p)]
+		if m.sizeOf(i) == 2 {
+			s += "\n"
+		}
+		indent := strings.Repeat("  ", len(p)-2)
+		title := ui.Title(t)
+		if i == m.cursor && m.mode == normalMode {
+			title = title.Copy().Background(ui.Faded).Foreground(ui.Background)
+		}
+		// the icon takes three columns, and a space is left before the bars
+		room := max(labelWidth-len(indent)-4, 1)
+		label := runewidth.Truncate(t.Title, room, "…")
+		s += indent + ui.RenderIcon(t) + title.Render(label) + strings.Repeat(" ", room-runewidth.StringWidth(label)+1)
+		s += m.renderBar(p) + "\n"
+	}
+	return s
+}
+
+// moveDates moves the start and due dates of a task by some days
+func (m *app) moveDates(id task.ID, days int) error {
+	t := m.all.Nodes[id]
+	for _, d := range []**time.Time{&t.Start, &t.Due} {
+		if *d != nil {
+			moved := (*d).AddDate(0, 0, days)
+			*d = &moved
+		}
+	}
+	return m.all.Set(id, t)
+}
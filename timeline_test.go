@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestColumn(t *testing.T) {
+	m := app{timeline: timeline{from: date(2026, 10, 1)}}
+	tests := []struct {
+		zoom int
+		day  time.Time
+		want int
+	}{
+		{0, date(2026, 10, 1), 0},
+		{0, date(2026, 10, 3), 6},
+		{0, date(2026, 9, 30), -3},
+		{1, date(2026, 10, 3), 2},
+		// a day is less than a column wide in months
+		{2, date(2026, 10, 3), 0},
+		{2, date(2026, 10, 9), 2},
+		{2, date(2026, 9, 30), -1},
+		// dates of the TUI are UTC midnight in local time
+		{0, date(2026, 10, 2).Local(), 3},
+	}
+	for _, tt := range tests {
+		m.timeline.zoom = tt.zoom
+		if got := m.column(tt.day); got != tt.want {
+			t.Errorf("%s zoom, %v: got %d, want %d", zooms[tt.zoom].name, tt.day, got, tt.want)
+		}
+	}
+}
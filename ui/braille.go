@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// dots are the bits of the braille dots in a cell, by row and column
+var dots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// Canvas is a grid of braille dots, two wide and four high in each cell, to
+// draw charts with a finer resolution than the terminal has
+type Canvas struct {
+	width, height int
+	cells         []rune
+	styles        []lipgloss.Style
+}
+
+// NewCanvas returns an empty canvas, width cells wide and height high
+func NewCanvas(width, height int) *Canvas {
+	return &Canvas{
+		width:  width,
+		height: height,
+		cells:  make([]rune, width*height),
+		styles: make([]lipgloss.Style, width*height),
+	}
+}
+
+// Size is how many dots fit across and down the canvas
+func (c *Canvas) Size() (int, int) {
+	return c.width * 2, c.height * 4
+}
+
+// Set draws a dot, counting from the top left. The cell takes the colour of
+// the last dot drawn in it.
+func (c *Canvas) Set(x, y int, style lipgloss.Style) {
+	if x < 0 || y < 0 || x >= c.width*2 || y >= c.height*4 {
+		return
+	}
+	i := y/4*c.width + x/2
+	c.cells[i] |= dots[y%4][x%2]
+	c.styles[i] = style
+}
+
+// Line draws a straight line between two dots
+func (c *Canvas) Line(x0, y0, x1, y1 int, style lipgloss.Style) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	for e := dx + dy; ; {
+		c.Set(x0, y0, style)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// Rows renders the canvas, a line for each row of cells
+func (c *Canvas) Rows() []string {
+	rows := make([]string, c.height)
+	for y := range rows {
+		var b strings.Builder
+		for x := 0; x < c.width; x++ {
+			i := y*c.width + x
+			if c.cells[i] == 0 {
+				b.WriteRune(' ')
+				continue
+			}
+			b.WriteString(c.styles[i].Render(string(0x2800 + c.cells[i])))
+		}
+		rows[y] = b.String()
+	}
+	return rows
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,44 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	sparks   = []rune("▁▂▃▄▅▆▇█")
+	barStyle = lipgloss.NewStyle().Foreground(Secondary)
+	barTrack = lipgloss.NewStyle().Foreground(Faded)
+)
+
+// Sparkline renders values as a line of bars of growing height, scaled to the
+// largest of them
+func Sparkline(values []int) string {
+	top := 0
+	for _, v := range values {
+		if v > top {
+			top = v
+		}
+	}
+	line := make([]rune, len(values))
+	for i, v := range values {
+		line[i] = sparks[0]
+		if top > 0 && v > 0 {
+			line[i] = sparks[(v*(len(sparks)-1)+top-1)/top]
+		}
+	}
+	return barStyle.Render(string(line))
+}
+
+// Bar renders value out of total as a bar width columns wide
+func Bar(value, total, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = value * width / total
+	}
+	if filled > width {
+		filled = width
+	}
+	return barStyle.Render(strings.Repeat("█", filled)) + barTrack.Render(strings.Repeat("░", width-filled))
+}
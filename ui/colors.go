@@ -35,6 +35,9 @@ var (
 	dueYellow = due.Copy().Foreground(Yellow)
 	dueOrange = due.Copy().Foreground(Orange)
 
+	progress         = lipgloss.NewStyle().Foreground(Secondary)
+	progressComplete = progress.Copy().Foreground(Green)
+
 	divider = lipgloss.NewStyle().Padding(0, 1).Foreground(Faded).Render("•")
 )
 
@@ -48,6 +51,20 @@ func RenderIcon(t task.Task) string {
 	return undone
 }
 
+// RenderProgress renders how many of the subtasks of a task are done, with a
+// bar too when they are folded out of sight
+func RenderProgress(done, total int, folded bool) string {
+	style := progress
+	if done == total {
+		style = progressComplete
+	}
+	s := style.Render(strconv.Itoa(done) + "/" + strconv.Itoa(total))
+	if folded {
+		s = Bar(done, total, 5) + " " + s
+	}
+	return divider + s
+}
+
 func Title(t task.Task) lipgloss.Style {
 	if t.Done != nil {
 		return titleDone
@@ -97,3 +114,32 @@ func dateToString(t time.Time) string {
 		return strconv.Itoa(months) + " month" + postfix
 	}
 }
+
+var errorBanner = lipgloss.NewStyle().Foreground(Primary).Background(Red).Padding(0, 1)
+
+// RenderError renders a failure as a one line banner
+func RenderError(err error) string {
+	return errorBanner.Render("✗ "+err.Error()) + lipgloss.NewStyle().Foreground(Secondary).Render(" esc to dismiss")
+}
+
+var notice = lipgloss.NewStyle().Foreground(Yellow)
+
+// RenderNotice renders a message for the status line
+func RenderNotice(s string) string {
+	return notice.Render(s)
+}
+
+var (
+	sectionStyle = lipgloss.NewStyle().Bold(true).Foreground(Secondary)
+	pathStyle    = lipgloss.NewStyle().Foreground(Faded)
+)
+
+// RenderSection renders the heading of a group of tasks
+func RenderSection(s string) string {
+	return sectionStyle.Render(s)
+}
+
+// RenderPath renders where a task is in the tree, next to its title
+func RenderPath(s string) string {
+	return divider + pathStyle.Render(s)
+}
@@ -37,29 +37,99 @@ func (m Tabs) Init() tea.Cmd {
 
 // Update is called when a message is received. Use it to inspect messages
 // and, in response, update the model and/or send a command.
-func (m Tabs) Update(_ tea.Msg) (Tabs, tea.Cmd) {
-	// panic("not implemented") // TODO: Implement
+func (m Tabs) Update(msg tea.Msg) (Tabs, tea.Cmd) {
+	if msg, ok := msg.(tea.MouseMsg); ok && msg.Type == tea.MouseLeft {
+		if i, ok := m.At(msg.X, msg.Y); ok {
+			m.Set(i)
+		}
+	}
 	return m, nil
 }
 
+// At returns the tab rendered at x and y by View, if any
+func (m Tabs) At(x, y int) (int, bool) {
+	// the tabs are on the line below the padding, and start after it
+	if y != 1 {
+		return 0, false
+	}
+	from, to := m.window(m.Width - 2 - lipgloss.Width(m.Info) - 1)
+	left := 1
+	if from > 0 {
+		left += 2
+	}
+	for i := from; i < to; i++ {
+		w := lipgloss.Width(m.tabs[i])
+		if x >= left && x < left+w {
+			return i, true
+		}
+		left += w + 3
+	}
+	return 0, false
+}
+
 // View renders the program's UI, which is just a string. The view is
 // rendered after every Update.
 func (m Tabs) View() string {
-	tabs := make([]string, len(m.tabs))
-	for i, t := range m.tabs {
+	w := lipgloss.Width
+	right := m.Info
+	from, to := m.window(m.Width - 2 - w(right) - 1)
+	tabs := []string{}
+	for i := from; i < to; i++ {
 		r := inactiveTab
 		if i == m.i {
 			r = activeTab
 		}
-		tabs[i] = r.Render(t)
+		tabs = append(tabs, r.Render(m.tabs[i]))
 	}
-	w := lipgloss.Width
 	left := strings.Join(tabs, " | ")
-	right := m.Info
-	space := lipgloss.NewStyle().Width(m.Width - 2 - w(left) - w(right)).Render("")
+	if from > 0 {
+		left = inactiveTab.Render("‹ ") + left
+	}
+	if to < len(m.tabs) {
+		left += inactiveTab.Render(" ›")
+	}
+	space := lipgloss.NewStyle().Width(max(m.Width-2-w(left)-w(right), 0)).Render("")
 	return tabContainer.Render(lipgloss.JoinHorizontal(lipgloss.Center, left, space, right)) + "\n"
 }
 
+// window picks the tabs that fit in width, spreading out from the active one
+// and leaving room for the arrows that say there are more
+func (m Tabs) window(width int) (int, int) {
+	if len(m.tabs) == 0 {
+		return 0, 0
+	}
+	const arrows = 4
+	from, to := m.i, m.i+1
+	used := lipgloss.Width(m.tabs[m.i])
+	fits := func(tab string) bool {
+		return used+3+lipgloss.Width(tab)+arrows <= width
+	}
+	for grew := true; grew; {
+		grew = false
+		if to < len(m.tabs) && fits(m.tabs[to]) {
+			used += 3 + lipgloss.Width(m.tabs[to])
+			to++
+			grew = true
+		}
+		if from > 0 && fits(m.tabs[from-1]) {
+			from--
+			used += 3 + lipgloss.Width(m.tabs[from])
+			grew = true
+		}
+	}
+	// every tab fits without the arrows
+	if from > 0 || to < len(m.tabs) {
+		total := 0
+		for _, t := range m.tabs {
+			total += lipgloss.Width(t) + 3
+		}
+		if total-3 <= width {
+			return 0, len(m.tabs)
+		}
+	}
+	return from, to
+}
+
 func (m Tabs) Value() int {
 	return m.i
 }
@@ -68,6 +138,12 @@ func (m Tabs) LastChanged() time.Time {
 	return m.lastChanged
 }
 
+// SetTabs replaces the tabs, keeping the active one in range
+func (m *Tabs) SetTabs(tabs []string) {
+	m.tabs = tabs
+	m.i = min(max(m.i, 0), len(tabs)-1)
+}
+
 func (m *Tabs) Set(i int) {
 	m.i = min(max(i, 0), len(m.tabs)-1)
 	m.lastChanged = time.Now()